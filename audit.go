@@ -0,0 +1,319 @@
+package minitel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Ops recorded in an AuditRecord.
+const (
+	AuditNotify   = "notify"
+	AuditFollowup = "followup"
+)
+
+// defaultAuditMaxSize is the size an audit file grows to before the next is
+// started.
+const defaultAuditMaxSize = 100 << 20
+
+var errAuditLogClosed = errors.New("minitel: Audit log is closed")
+
+// AuditRecord describes a single Notify or Followup call on a Client, or one
+// Notification sent with NotifyBatch.
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+	Op   string    `json:"op"`
+
+	// Target of a Notification. Records read with ReadAuditLog also have the
+	// Target of the thread a Followup was added to, when it is in the log.
+	Target Target `json:"target"`
+
+	// MessageID is the thread a Followup was added to.
+	MessageID string `json:"message_id,omitempty"`
+
+	Title string `json:"title,omitempty"`
+
+	// Body as sent. AuditLog replaces it with BodySHA256 unless
+	// WithAuditFullBody is used.
+	Body       string `json:"body,omitempty"`
+	BodySHA256 string `json:"body_sha256,omitempty"`
+
+	ResultID string `json:"result_id,omitempty"`
+
+	// Status of the Telex response, 0 if there wasn't one.
+	Status     int    `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DryRun     bool   `json:"dry_run,omitempty"`
+	Suppressed bool   `json:"suppressed,omitempty"`
+
+	// Latency of the call, for NotifyBatch that of the whole batch.
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// WithAuditHook sets a func called with an AuditRecord after every Notify and
+// Followup call, and for every Notification passed to NotifyBatch, whether it
+// succeeded or not. See AuditLog.Record.
+func WithAuditHook(f func(AuditRecord)) Option {
+	return func(c *Client) error {
+		c.audit = f
+		return nil
+	}
+}
+
+// record the outcome of a call that started at start with the audit hook.
+func (c *Client) record(r AuditRecord, start time.Time, result Result, err error) {
+	r.Time = start
+	r.Latency = time.Since(start)
+	r.ResultID = result.ID
+	r.DryRun = result.DryRun
+	r.Suppressed = result.Suppressed
+
+	var se *StatusError
+	switch {
+	case err == nil && !result.DryRun && !result.Suppressed:
+		r.Status = http.StatusCreated
+	case errors.As(err, &se):
+		r.Status = se.Got
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	c.audit(r)
+}
+
+// AuditOption configures an AuditLog.
+type AuditOption func(*AuditLog)
+
+// WithAuditMaxSize sets the size in bytes an audit file grows to before the
+// next is started. Defaults to 100MiB.
+func WithAuditMaxSize(n int64) AuditOption {
+	return func(l *AuditLog) {
+		l.maxSize = n
+	}
+}
+
+// WithAuditFullBody sets whether the full body is logged rather than its
+// SHA-256 hash.
+func WithAuditFullBody(full bool) AuditOption {
+	return func(l *AuditLog) {
+		l.fullBody = full
+	}
+}
+
+// WithAuditErrorHandler sets a func called with errors writing records, as
+// the audit hook can't return them.
+func WithAuditErrorHandler(f func(error)) AuditOption {
+	return func(l *AuditLog) {
+		l.onError = f
+	}
+}
+
+// AuditLog is an append-only log of AuditRecords in a directory of JSONL
+// files. A new file is started for each day's records, in UTC, and whenever
+// the current one reaches the maximum size. Files are named
+// audit-YYYY-MM-DD-NNN.jsonl so they sort in the order they were written.
+type AuditLog struct {
+	dir      string
+	maxSize  int64
+	fullBody bool
+	onError  func(error)
+
+	mu     sync.Mutex
+	f      *os.File
+	day    string
+	size   int64
+	closed bool
+}
+
+// NewAuditLog returns an AuditLog writing to dir, which is created if needed.
+// Use its Record method with WithAuditHook.
+func NewAuditLog(dir string, opts ...AuditOption) (*AuditLog, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	l := &AuditLog{
+		dir:     dir,
+		maxSize: defaultAuditMaxSize,
+		onError: func(error) {},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+// Record appends r to the log.
+func (l *AuditLog) Record(r AuditRecord) {
+	if err := l.write(r); err != nil {
+		l.onError(err)
+	}
+}
+
+func (l *AuditLog) write(r AuditRecord) error {
+	if !l.fullBody {
+		sum := sha256.Sum256([]byte(r.Body))
+		r.Body, r.BodySHA256 = "", hex.EncodeToString(sum[:])
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return errAuditLogClosed
+	}
+	day := r.Time.UTC().Format("2006-01-02")
+	if l.f == nil || day != l.day || (l.maxSize > 0 && l.size+int64(len(b)) > l.maxSize) {
+		if err := l.rotate(day, int64(len(b))); err != nil {
+			return err
+		}
+	}
+	n, err := l.f.Write(b)
+	l.size += int64(n)
+	return err
+}
+
+// rotate to the first file of day with room for n more bytes.
+func (l *AuditLog) rotate(day string, n int64) error {
+	if l.f != nil {
+		if err := l.f.Close(); err != nil {
+			return err
+		}
+		l.f = nil
+	}
+
+	for seq := 0; ; seq++ {
+		path := filepath.Join(l.dir, fmt.Sprintf("audit-%s-%03d.jsonl", day, seq))
+		fi, err := os.Stat(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		var size int64
+		if fi != nil {
+			size = fi.Size()
+		}
+		if fi != nil && l.maxSize > 0 && size > 0 && size+n > l.maxSize {
+			continue
+		}
+
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		l.f, l.day, l.size = f, day, size
+		return nil
+	}
+}
+
+// Close the current file. Later records fail.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.closed = true
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// AuditQuery selects AuditRecords. Zero fields match every record.
+type AuditQuery struct {
+	// Target matches records for a Target Type, ID or both.
+	Target Target
+
+	// Since and Until bound the Time of records, Until exclusively.
+	Since, Until time.Time
+}
+
+func (q AuditQuery) matches(r AuditRecord) bool {
+	if q.Target.Type != "" && q.Target.Type != r.Target.Type {
+		return false
+	}
+	if q.Target.ID != "" && q.Target.ID != r.Target.ID {
+		return false
+	}
+	if !q.Since.IsZero() && r.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !r.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// ReadAuditLog returns the records in the AuditLog in dir matching q, in the
+// order they were written.
+func ReadAuditLog(dir string, q AuditQuery) ([]AuditRecord, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, fi := range infos {
+		if strings.HasPrefix(fi.Name(), "audit-") && strings.HasSuffix(fi.Name(), ".jsonl") {
+			names = append(names, fi.Name())
+		}
+	}
+	sort.Strings(names)
+
+	var records []AuditRecord
+	threads := make(map[string]Target)
+	for _, name := range names {
+		err := readAuditFile(filepath.Join(dir, name), func(r AuditRecord) {
+			switch r.Op {
+			case AuditNotify:
+				if r.ResultID != "" {
+					threads[r.ResultID] = r.Target
+				}
+			case AuditFollowup:
+				r.Target = threads[r.MessageID]
+			}
+			if q.matches(r) {
+				records = append(records, r)
+			}
+		})
+		if err != nil {
+			return records, err
+		}
+	}
+	return records, nil
+}
+
+func readAuditFile(path string, f func(AuditRecord)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Decoded as a stream rather than by line, as records with full bodies
+	// have no size limit.
+	dec := json.NewDecoder(file)
+	for {
+		var r AuditRecord
+		err := dec.Decode(&r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("minitel: %s: %w", path, err)
+		}
+		f(r)
+	}
+}
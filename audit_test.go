@@ -0,0 +1,207 @@
+package minitel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestAuditHook(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(
+		miniteltest.GenerateHTTPResponse(t, "thread-id", http.StatusCreated),
+		miniteltest.GenerateHTTPResponse(t, "", http.StatusServiceUnavailable),
+	)
+	ts.ExpectFollowup(nil)
+
+	dir, err := ioutil.TempDir("", "minitel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewAuditLog(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c, err := New(ts.URL, WithAuditHook(l.Record))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := testNotifications(2)
+	ns[1].Target.ID = "bc31ed62-0204-40e5-86cf-b25a001b20db"
+	if _, err := c.Notify(ns[0]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Followup("thread-id", "More detail"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Notify(ns[1]); err == nil {
+		t.Fatal("expected the second notification to fail")
+	}
+
+	records, err := ReadAuditLog(dir, AuditQuery{Target: ns[0].Target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected the notification and its followup, got %+v", records)
+	}
+	if r := records[0]; r.Op != AuditNotify || r.ResultID != "thread-id" || r.Status != http.StatusCreated || r.Title != ns[0].Title {
+		t.Errorf("unexpected notify record: %+v", r)
+	}
+	if r := records[1]; r.Op != AuditFollowup || r.MessageID != "thread-id" || r.Body != "" || r.BodySHA256 == "" {
+		t.Errorf("expected a followup record with a hashed body, got %+v", r)
+	}
+
+	records, err = ReadAuditLog(dir, AuditQuery{Target: ns[1].Target})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Status != http.StatusServiceUnavailable || records[0].Error == "" {
+		t.Errorf("expected the failure to be recorded, got %+v", records)
+	}
+}
+
+func TestAuditBatch(t *testing.T) {
+	for _, batch := range []bool{true, false} {
+		ts := miniteltest.NewServer()
+		defer ts.Close()
+		if !batch {
+			ts.DisableBatch()
+		}
+		ts.ExpectNotify(
+			miniteltest.GenerateHTTPResponse(t, "first-id", http.StatusCreated),
+			miniteltest.GenerateHTTPResponse(t, "", http.StatusServiceUnavailable),
+		)
+
+		dir, err := ioutil.TempDir("", "minitel")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		l, err := NewAuditLog(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer l.Close()
+		c, err := New(ts.URL, WithAuditHook(l.Record))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ns := testNotifications(3)
+		ns[2].Target.ID = ""
+		if _, err := c.NotifyBatch(ns); err == nil {
+			t.Fatal("expected a BatchError")
+		}
+
+		records, err := ReadAuditLog(dir, AuditQuery{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 3 {
+			t.Fatalf("batch %t: expected a record per notification, got %+v", batch, records)
+		}
+		if r := records[0]; r.ResultID != "first-id" || r.Status != http.StatusCreated {
+			t.Errorf("batch %t: unexpected record for the sent notification: %+v", batch, r)
+		}
+		if r := records[1]; r.Status != http.StatusServiceUnavailable || r.Error == "" {
+			t.Errorf("batch %t: unexpected record for the failed notification: %+v", batch, r)
+		}
+		if r := records[2]; r.Status != 0 || r.Error != errNoID.Error() {
+			t.Errorf("batch %t: unexpected record for the invalid notification: %+v", batch, r)
+		}
+	}
+}
+
+func TestAuditLogRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minitel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewAuditLog(dir, WithAuditMaxSize(400), WithAuditFullBody(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 10, 18, 22, 0, 0, 0, time.UTC)
+	target := testNotification().Target
+	for i := 0; i < 6; i++ {
+		l.Record(AuditRecord{
+			Time:   start.Add(time.Duration(i) * time.Hour),
+			Op:     AuditNotify,
+			Target: target,
+			Body:   "Full body",
+		})
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 3 || filepath.Base(files[0]) != "audit-2026-10-18-000.jsonl" {
+		t.Errorf("expected files to rotate by size and day, got %q", files)
+	}
+
+	records, err := ReadAuditLog(dir, AuditQuery{Since: start.Add(time.Hour), Until: start.Add(4 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records in range, got %d", len(records))
+	}
+	if !records[0].Time.Equal(start.Add(time.Hour)) || records[0].Body != "Full body" {
+		t.Errorf("unexpected first record: %+v", records[0])
+	}
+
+	var errored bool
+	l, _ = NewAuditLog(dir, WithAuditErrorHandler(func(error) { errored = true }))
+	l.Close()
+	l.Record(AuditRecord{Time: start})
+	if !errored {
+		t.Error("expected recording to a closed log to fail")
+	}
+}
+
+func TestAuditLogLargeBody(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minitel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	l, err := NewAuditLog(dir, WithAuditFullBody(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	body := strings.Repeat("release log line\n", 2<<20/17)
+	l.Record(AuditRecord{Time: start, Op: AuditFollowup, MessageID: "release-id", Body: body})
+	l.Record(AuditRecord{Time: start.Add(time.Minute), Op: AuditFollowup, MessageID: "release-id", Body: "Done"})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ReadAuditLog(dir, AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].Body != body || records[1].Body != "Done" {
+		t.Errorf("expected both records to be read, got %d", len(records))
+	}
+}
@@ -0,0 +1,209 @@
+package minitel
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// defaultBatchSize is the number of Notifications sent per batch request
+// unless changed with WithBatchSize.
+const defaultBatchSize = 100
+
+// WithBatchSize sets the maximum number of Notifications NotifyBatch packs into
+// a single request.
+func WithBatchSize(n int) Option {
+	return func(c *Client) error {
+		if n <= 0 {
+			return fmt.Errorf("minitel: batch size must be positive: %d", n)
+		}
+		c.batchSize = n
+		return nil
+	}
+}
+
+// BatchError is returned by NotifyBatch when some of the Notifications could
+// not be sent. It is aligned with the Notifications passed to NotifyBatch, with
+// nil entries for those that were sent.
+type BatchError []error
+
+func (e BatchError) Error() string {
+	var failed int
+	var first error
+	for _, err := range e {
+		if err == nil {
+			continue
+		}
+		if first == nil {
+			first = err
+		}
+		failed++
+	}
+	return fmt.Sprintf("minitel: %d of %d notifications failed, first error: %s", failed, len(e), first)
+}
+
+// batchItem is the per Notification response to a batch request.
+type batchItem struct {
+	ID     string `json:"id"`
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// NotifyBatch sends ns to Telex, packing them into batch requests of up to the
+// configured batch size. If Telex does not support batch requests each
+// Notification is sent on its own instead. The Results are aligned with ns. If
+// any Notification could not be sent the error is a BatchError.
+func (c *Client) NotifyBatch(ns []Notification) ([]Result, error) {
+	start := time.Now()
+	results := make([]Result, len(ns))
+	errs := make(BatchError, len(ns))
+
+	// Prepare up front so invalid Notifications don't fail a whole batch.
+	prepared := make([]Notification, len(ns))
+	if c.audit != nil {
+		defer func() {
+			for i, n := range prepared {
+				c.record(AuditRecord{Op: AuditNotify, Target: n.Target, Title: n.Title, Body: n.Body}, start, results[i], errs[i])
+			}
+		}()
+	}
+	var pending []int
+	for i, n := range ns {
+		var err error
+		prepared[i], err = c.prepare(n, true)
+		if err == errSuppressed {
+			results[i] = Result{Suppressed: true}
+			continue
+		}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		pending = append(pending, i)
+	}
+	ns = prepared
+
+	for len(pending) > 0 {
+		chunk := pending
+		if len(chunk) > c.batchSize {
+			chunk = chunk[:c.batchSize]
+		}
+		pending = pending[len(chunk):]
+
+		if atomic.LoadInt32(&c.batchUnsupported) == 0 {
+			err := c.sendBatch(ns, chunk, results, errs)
+			if !isBatchUnsupported(err) {
+				continue
+			}
+			atomic.StoreInt32(&c.batchUnsupported, 1)
+		}
+
+		for _, i := range chunk {
+			results[i], errs[i] = c.send(ns[i])
+		}
+	}
+
+	for i, err := range errs {
+		if err == nil {
+			c.environmentSent(results[i])
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return results, errs
+		}
+	}
+	return results, nil
+}
+
+// sendBatch sends the Notifications in ns identified by chunk as one batch
+// request, filling in results and errs. A returned error means nothing was
+// recorded for the chunk.
+func (c *Client) sendBatch(ns []Notification, chunk []int, results []Result, errs BatchError) error {
+	batch := make([]Notification, len(chunk))
+	for j, i := range chunk {
+		batch[j] = ns[i]
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(batch); err != nil {
+		return err
+	}
+
+	req, err := c.NewRequest(http.MethodPost, "/producer/messages/batch", &buf)
+	if err != nil {
+		return err
+	}
+
+	if c.dryRun != nil {
+		err := c.dryRunRequest(req)
+		for _, i := range chunk {
+			results[i], errs[i] = Result{DryRun: true}, err
+		}
+		return nil
+	}
+
+	items, err := c.doBatch(req)
+	if err == nil && len(items) != len(chunk) {
+		err = fmt.Errorf("minitel: Expected %d batch results: Got %d", len(chunk), len(items))
+	}
+	if err != nil {
+		if isBatchUnsupported(err) {
+			return err
+		}
+		for _, i := range chunk {
+			errs[i] = err
+		}
+		return nil
+	}
+
+	for j, i := range chunk {
+		item := items[j]
+		switch {
+		case item.Error != "":
+			errs[i] = fmt.Errorf("minitel: %s", item.Error)
+		case item.Status != 0 && item.Status != http.StatusCreated:
+			errs[i] = &StatusError{Expected: http.StatusCreated, Got: item.Status}
+		default:
+			results[i] = Result{ID: item.ID}
+		}
+	}
+	return nil
+}
+
+func (c *Client) doBatch(req *http.Request) (items []batchItem, err error) {
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, c.Redact(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, &StatusError{Expected: http.StatusCreated, Got: resp.StatusCode}
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	if err := dec.Decode(&items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// isBatchUnsupported reports whether err indicates Telex doesn't provide the
+// batch endpoint.
+func isBatchUnsupported(err error) bool {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return false
+	}
+	switch se.Got {
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package minitel
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestNotifyBatch(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		disableBatch bool
+		wantBatches  int
+	}{
+		{name: "Batch", wantBatches: 3},
+		{name: "Fallback", disableBatch: true, wantBatches: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := miniteltest.NewServer()
+			defer ts.Close()
+			if tc.disableBatch {
+				ts.DisableBatch()
+			}
+
+			ns := testNotifications(5)
+			ids := []string{"id0", "id1", "id2", "id3", "id4"}
+			for _, id := range ids {
+				ts.ExpectNotify(miniteltest.GenerateHTTPResponse(t, id, http.StatusCreated))
+			}
+
+			c, err := New(ts.URL, WithBatchSize(2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			results, err := c.NotifyBatch(ns)
+			if err != nil {
+				t.Fatal("unexpected error: ", err)
+			}
+			for i, id := range ids {
+				if results[i].ID != id {
+					t.Errorf("expected result %d to have ID %q, got %q", i, id, results[i].ID)
+				}
+			}
+			if got := ts.BatchRequests(); got != tc.wantBatches {
+				t.Errorf("expected %d batch requests, got %d", tc.wantBatches, got)
+			}
+			if finished := ts.ExpectDone(time.Second); !finished {
+				t.Error("expected no pending expectations, but some still exist")
+			}
+		})
+	}
+}
+
+func TestNotifyBatchErrors(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+
+	ns := testNotifications(3)
+	ns[1].Target.ID = "abc"
+	ts.ExpectNotify(
+		miniteltest.GenerateHTTPResponse(t, "id0", http.StatusCreated),
+		miniteltest.GenerateHTTPResponse(t, "", http.StatusUnprocessableEntity),
+	)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := c.NotifyBatch(ns)
+	errs, ok := err.(BatchError)
+	if !ok {
+		t.Fatalf("expected BatchError, got %T: %v", err, err)
+	}
+	if len(errs) != len(ns) || len(results) != len(ns) {
+		t.Fatalf("expected results and errors aligned with %d notifications", len(ns))
+	}
+	if errs[0] != nil || results[0].ID != "id0" {
+		t.Errorf("expected first notification to be sent, got %+v, %v", results[0], errs[0])
+	}
+	if errs[1] != errIDNotUUID {
+		t.Errorf("expected second notification to fail validation, got %v", errs[1])
+	}
+	if se, ok := errs[2].(*StatusError); !ok || se.Got != http.StatusUnprocessableEntity {
+		t.Errorf("expected third notification to fail with a 422, got %v", errs[2])
+	}
+}
+
+func TestWithBatchSize(t *testing.T) {
+	if _, err := New("http://localhost", WithBatchSize(0)); err == nil {
+		t.Error("expected error for batch size 0 but was nil")
+	}
+}
@@ -0,0 +1,251 @@
+// Package bridge forwards Prometheus Alertmanager webhook notifications to
+// Telex. Firing alerts create a Telex notification for the Target their labels
+// map to, and their resolution is added to it as a followup. Repeat
+// notifications of an alert that is still firing are ignored, so that
+// Alertmanager can retry a webhook request without duplicating anything.
+package bridge
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	minitel "github.com/heroku/minitel-go"
+)
+
+// Default templates for the Title and Body of notifications.
+const (
+	DefaultTitle = `[{{ .Status | toUpper }}] {{ index .Labels "alertname" }}`
+	DefaultBody  = `{{ with index .Annotations "summary" }}{{ . }}{{ end }}` +
+		`{{ with index .Annotations "description" }}{{ "\n" }}{{ . }}{{ end }}`
+)
+
+// Alertmanager alert statuses.
+const (
+	Firing   = "firing"
+	Resolved = "resolved"
+)
+
+// Message is the body of an Alertmanager webhook request.
+type Message struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// Alert in a Message. It is the data the Title and Body templates are
+// executed with.
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// key identifies the alert across webhook requests.
+func (a Alert) key() string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	names := make([]string, 0, len(a.Labels))
+	for name := range a.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s=%q,", name, a.Labels[name])
+	}
+	return b.String()
+}
+
+// Rule maps alerts to a Target. An alert matches a Rule when it has every label
+// in Match with the given value. The Target's ID is taken from the IDLabel
+// label of the alert, or is ID if IDLabel is empty.
+type Rule struct {
+	Match   map[string]string `json:"match"`
+	Type    minitel.Type      `json:"type"`
+	IDLabel string            `json:"id_label"`
+	ID      string            `json:"id"`
+}
+
+func (r Rule) target(a Alert) (minitel.Target, bool) {
+	for name, value := range r.Match {
+		if a.Labels[name] != value {
+			return minitel.Target{}, false
+		}
+	}
+	t := minitel.Target{Type: r.Type, ID: r.ID}
+	if r.IDLabel != "" {
+		t.ID = a.Labels[r.IDLabel]
+	}
+	return t, t.ID != ""
+}
+
+// Config of a Bridge.
+type Config struct {
+	// Rules are tried in order, the first matching an alert decides its
+	// Target. Alerts matching no rule are ignored.
+	Rules []Rule `json:"rules"`
+
+	// Title and Body are text/template templates executed with an Alert.
+	// DefaultTitle and DefaultBody are used when empty.
+	Title string `json:"title"`
+	Body  string `json:"body"`
+
+	// ActionLabel is the label of the Action linking to the alert's
+	// GeneratorURL. Defaults to "View in Prometheus".
+	ActionLabel string `json:"action_label"`
+}
+
+// Bridge is a http.Handler accepting Alertmanager webhook requests.
+type Bridge struct {
+	incidents   *minitel.IncidentManager
+	store       minitel.IncidentStore
+	rules       []Rule
+	title, body *template.Template
+	actionLabel string
+}
+
+// New Bridge sending through n, remembering the thread of each alert in store.
+func New(n minitel.Notifier, store minitel.IncidentStore, cfg Config) (*Bridge, error) {
+	if cfg.Title == "" {
+		cfg.Title = DefaultTitle
+	}
+	if cfg.Body == "" {
+		cfg.Body = DefaultBody
+	}
+	if cfg.ActionLabel == "" {
+		cfg.ActionLabel = "View in Prometheus"
+	}
+
+	funcs := template.FuncMap{"toUpper": strings.ToUpper}
+	title, err := template.New("title").Funcs(funcs).Parse(cfg.Title)
+	if err != nil {
+		return nil, err
+	}
+	body, err := template.New("body").Funcs(funcs).Parse(cfg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bridge{
+		incidents:   minitel.NewIncidentManager(n, store),
+		store:       store,
+		rules:       cfg.Rules,
+		title:       title,
+		body:        body,
+		actionLabel: cfg.ActionLabel,
+	}, nil
+}
+
+// ServeHTTP handles an Alertmanager webhook request. It responds with a 500 if
+// any alert could not be forwarded, so that Alertmanager retries.
+func (b *Bridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Unexpected Method: "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg Message
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := b.Handle(msg); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Handle forwards every alert in msg, returning the first error encountered.
+// Alerts forwarded before are skipped, so the whole of msg can be retried.
+func (b *Bridge) Handle(msg Message) error {
+	var first error
+	for _, a := range msg.Alerts {
+		if err := b.forward(a); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (b *Bridge) forward(a Alert) error {
+	target, ok := b.target(a)
+	if !ok {
+		return nil
+	}
+
+	title, err := execute(b.title, a)
+	if err != nil {
+		return err
+	}
+	body, err := execute(b.body, a)
+	if err != nil {
+		return err
+	}
+
+	if a.Status == Resolved {
+		_, err := b.incidents.Resolve(a.key(), title+"\n"+body)
+		if errors.Is(err, minitel.ErrUnknownIncident) {
+			// Resolved before we saw it firing, nothing to close.
+			return nil
+		}
+		return err
+	}
+
+	// Already notified, by a repeat or an earlier attempt at this request.
+	if _, ok, err := b.store.Get(a.key()); err != nil || ok {
+		return err
+	}
+	n := minitel.Notification{
+		Title:  title,
+		Body:   body,
+		Target: target,
+	}
+	if a.GeneratorURL != "" {
+		n.Action = minitel.Action{Label: b.actionLabel, URL: a.GeneratorURL}
+	}
+	switch s := minitel.Severity(a.Labels["severity"]); s {
+	case minitel.Info, minitel.Warning, minitel.Critical:
+		n.Severity = s
+	}
+
+	_, err = b.incidents.Event(a.key(), n)
+	return err
+}
+
+func (b *Bridge) target(a Alert) (minitel.Target, bool) {
+	for _, r := range b.rules {
+		if t, ok := r.target(a); ok {
+			return t, true
+		}
+	}
+	return minitel.Target{}, false
+}
+
+func execute(t *template.Template, a Alert) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, a); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
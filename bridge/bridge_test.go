@@ -0,0 +1,164 @@
+package bridge
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	minitel "github.com/heroku/minitel-go"
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+const appID = "93f90f07-bbe3-433d-806d-2d01bc5ae1f2"
+
+func webhook(status string) string {
+	return `{
+		"version": "4",
+		"status": "` + status + `",
+		"alerts": [
+			{
+				"status": "` + status + `",
+				"labels": {"alertname": "HighErrorRate", "app_id": "` + appID + `", "severity": "critical"},
+				"annotations": {"summary": "Error rate above 5%", "description": "Check the logs."},
+				"generatorURL": "https://prometheus.example.com/graph",
+				"fingerprint": "abc123"
+			},
+			{
+				"status": "` + status + `",
+				"labels": {"alertname": "Unrouted"},
+				"fingerprint": "def456"
+			}
+		]
+	}`
+}
+
+func post(t *testing.T, b *Bridge, body string) int {
+	w := httptest.NewRecorder()
+	b.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	return w.Code
+}
+
+func TestBridge(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(miniteltest.GenerateHTTPResponse(t, "thread-id", http.StatusCreated))
+	ts.ExpectFollowup(nil)
+
+	c, err := minitel.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(c, minitel.NewMemoryIncidentStore(), Config{
+		Rules: []Rule{{Match: map[string]string{"alertname": "HighErrorRate"}, Type: minitel.App, IDLabel: "app_id"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Firing twice, as Alertmanager does on repeat_interval, then resolved.
+	for _, status := range []string{Firing, Firing, Resolved} {
+		if code := post(t, b, webhook(status)); code != http.StatusOK {
+			t.Fatalf("expected 200 for %s webhook, got %d", status, code)
+		}
+	}
+
+	got := ts.Notifications()
+	if len(got) != 1 {
+		t.Fatalf("expected a single notification, got %d", len(got))
+	}
+	n := got[0]
+	if n.Title != "[FIRING] HighErrorRate" || n.Body != "Error rate above 5%\nCheck the logs." {
+		t.Errorf("unexpected rendering: %q, %q", n.Title, n.Body)
+	}
+	if n.Target.Type != "app" || n.Target.ID != appID || n.Severity != "critical" {
+		t.Errorf("unexpected target or severity: %+v", n)
+	}
+	if n.Action.URL != "https://prometheus.example.com/graph" {
+		t.Errorf("expected action to link to the generator URL, got %+v", n.Action)
+	}
+	if finished := ts.ExpectDone(time.Second); !finished {
+		t.Error("expected the resolution to be sent as a followup")
+	}
+	followups := ts.Followups()
+	if len(followups) != 1 {
+		t.Fatalf("expected only the resolution as a followup, got %+v", followups)
+	}
+	if f := followups[0]; f.ID != "thread-id" || f.Body != "[RESOLVED] HighErrorRate\nError rate above 5%\nCheck the logs." {
+		t.Errorf("unexpected resolution followup: %+v", f)
+	}
+}
+
+func TestBridgeRetry(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(
+		miniteltest.GenerateHTTPResponse(t, "first-id", http.StatusCreated),
+		miniteltest.GenerateHTTPResponse(t, "", http.StatusServiceUnavailable),
+		miniteltest.GenerateHTTPResponse(t, "second-id", http.StatusCreated),
+	)
+
+	c, err := minitel.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both alerts are routed, so the second fails after the first is sent.
+	b, err := New(c, minitel.NewMemoryIncidentStore(), Config{
+		Rules: []Rule{{Type: minitel.App, ID: appID}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := post(t, b, webhook(Firing)); code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 when an alert fails, got %d", code)
+	}
+	if code := post(t, b, webhook(Firing)); code != http.StatusOK {
+		t.Fatalf("expected 200 for the retry, got %d", code)
+	}
+	if finished := ts.ExpectDone(time.Second); !finished {
+		t.Error("expected the failed alert to be sent again")
+	}
+
+	got := ts.Notifications()
+	if len(got) != 3 || got[0].Title != "[FIRING] HighErrorRate" || got[2].Title != "[FIRING] Unrouted" {
+		t.Errorf("expected only the failed alert to be retried, got %+v", got)
+	}
+	if followups := ts.Followups(); len(followups) != 0 {
+		t.Errorf("expected the retry not to add followups, got %+v", followups)
+	}
+}
+
+func TestBridgeErrors(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+
+	c, err := minitel.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := New(c, minitel.NewMemoryIncidentStore(), Config{
+		Rules: []Rule{{Type: minitel.App, ID: appID}},
+		Title: "{{ .Labels.alertname }}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := post(t, b, "not json"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid JSON, got %d", code)
+	}
+	// No Notify expectation, so Telex fails and Alertmanager should retry.
+	if code := post(t, b, webhook(Firing)); code != http.StatusInternalServerError {
+		t.Errorf("expected 500 when Telex fails, got %d", code)
+	}
+	// Resolving an alert never seen firing is not an error.
+	if code := post(t, b, webhook(Resolved)); code != http.StatusOK {
+		t.Errorf("expected 200 for unknown resolved alert, got %d", code)
+	}
+
+	if _, err := New(c, minitel.NewMemoryIncidentStore(), Config{Title: "{{"}); err == nil {
+		t.Error("expected error for invalid template but was nil")
+	}
+}
@@ -0,0 +1,131 @@
+package minitel
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	errEmptyTitle       = errors.New("minitel: Title must not be empty")
+	errEmptyLabel       = errors.New("minitel: Action.Label must not be empty")
+	errEmptyMetadataKey = errors.New("minitel: Metadata key must not be empty")
+)
+
+// BuildError holds every error found while building a Notification.
+type BuildError []error
+
+func (e BuildError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Builder constructs a Notification, collecting errors along the way so they
+// can be checked once when calling Build.
+//
+//	n, err := minitel.ForApp(id).
+//		Title("Your DB is on fire!").
+//		Body("...").
+//		Action("View Invoice", "https://view.your.invoice/yolo").
+//		Build()
+type Builder struct {
+	n    Notification
+	errs BuildError
+}
+
+// NewNotification returns a Builder for a Notification. Use To to set its
+// Target, or start from ForApp or ForUser instead.
+func NewNotification() *Builder {
+	return &Builder{}
+}
+
+// ForApp returns a Builder for a Notification targeted at the app with id.
+func ForApp(id string) *Builder {
+	return NewNotification().To(App, id)
+}
+
+// ForUser returns a Builder for a Notification targeted at the user with id.
+func ForUser(id string) *Builder {
+	return NewNotification().To(User, id)
+}
+
+// To sets the Target of the Notification.
+func (b *Builder) To(t Type, id string) *Builder {
+	b.n.Target = Target{Type: t, ID: id}
+	return b
+}
+
+// Title sets the Title of the Notification.
+func (b *Builder) Title(title string) *Builder {
+	if title == "" {
+		b.errs = append(b.errs, errEmptyTitle)
+	}
+	b.n.Title = title
+	return b
+}
+
+// Body sets the Body of the Notification.
+func (b *Builder) Body(body string) *Builder {
+	b.n.Body = body
+	return b
+}
+
+// Action sets the Action of the Notification. rawURL must be an absolute URL.
+func (b *Builder) Action(label, rawURL string) *Builder {
+	if label == "" {
+		b.errs = append(b.errs, errEmptyLabel)
+	}
+	if u, err := url.Parse(rawURL); err != nil || !u.IsAbs() {
+		b.errs = append(b.errs, fmt.Errorf("minitel: Action.URL must be an absolute URL: %q", rawURL))
+	}
+	b.n.Action = Action{Label: label, URL: rawURL}
+	return b
+}
+
+// Severity sets the Severity of the Notification.
+func (b *Builder) Severity(s Severity) *Builder {
+	b.n.Severity = s
+	return b
+}
+
+// Category sets the Category of the Notification.
+func (b *Builder) Category(c string) *Builder {
+	b.n.Category = c
+	return b
+}
+
+// Tags adds tags to the Notification.
+func (b *Builder) Tags(tags ...string) *Builder {
+	b.n.Tags = append(b.n.Tags, tags...)
+	return b
+}
+
+// Metadata sets the metadata key of the Notification to value.
+func (b *Builder) Metadata(key, value string) *Builder {
+	if key == "" {
+		b.errs = append(b.errs, errEmptyMetadataKey)
+		return b
+	}
+	if b.n.Metadata == nil {
+		b.n.Metadata = make(map[string]string)
+	}
+	b.n.Metadata[key] = value
+	return b
+}
+
+// Build the Notification, returning a BuildError holding every error found
+// by the Builder and by Notification.Validate.
+func (b *Builder) Build() (Notification, error) {
+	errs := append(BuildError(nil), b.errs...)
+	if err := b.n.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return Notification{}, errs
+	}
+	return b.n, nil
+}
@@ -0,0 +1,63 @@
+package minitel
+
+import (
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	n, err := ForApp("84838298-989d-4409-b148-6abef06df43f").
+		Title("Your DB is on fire!").
+		Body("...").
+		Action("View Invoice", "https://view.your.invoice/yolo").
+		Severity(Critical).
+		Category("database").
+		Tags("postgres", "outage").
+		Metadata("plan", "standard-0").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n.Target != (Target{Type: App, ID: "84838298-989d-4409-b148-6abef06df43f"}) {
+		t.Errorf("unexpected target: %+v", n.Target)
+	}
+	if n.Title != "Your DB is on fire!" || n.Body != "..." {
+		t.Errorf("unexpected title or body: %+v", n)
+	}
+	if n.Action != (Action{Label: "View Invoice", URL: "https://view.your.invoice/yolo"}) {
+		t.Errorf("unexpected action: %+v", n.Action)
+	}
+	if n.Severity != Critical || n.Category != "database" || len(n.Tags) != 2 || n.Metadata["plan"] != "standard-0" {
+		t.Errorf("unexpected optional fields: %+v", n)
+	}
+
+	u, err := ForUser("84838298-989d-4409-b148-6abef06df43f").Title("Hi").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Target.Type != User {
+		t.Errorf("expected user target, got %+v", u.Target)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	_, err := NewNotification().
+		Title("").
+		Action("", "/relative").
+		Severity("dire").
+		Build()
+
+	errs, ok := err.(BuildError)
+	if !ok {
+		t.Fatalf("expected BuildError, got %T: %v", err, err)
+	}
+	want := []error{errEmptyTitle, errEmptyLabel, nil, errNoID}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		if w != nil && errs[i] != w {
+			t.Errorf("expected error %d to be %v, got %v", i, w, errs[i])
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package minitel
+
+import "time"
+
+// Clock provides the current time and timers to the types in this package that
+// depend on them, so that tests can control the passing of time.
+// miniteltest.Clock is a Clock suitable for tests.
+type Clock interface {
+	Now() time.Time
+
+	// AfterFunc calls f in its own goroutine after d has elapsed. The returned
+	// stop func cancels the call, reporting false if it already happened.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+// SystemClock is the Clock used unless another is configured.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/heroku/minitel-go"
+	"github.com/heroku/minitel-go/bridge"
+)
+
+var configFile = flag.String("config", "", "Path to the bridge's JSON configuration")
+var listen = flag.String("listen", ":8080", "Address to accept Alertmanager webhooks on")
+var stateFile = flag.String("state", "", "Path to a file remembering alert threads across restarts")
+
+func getConfig() bridge.Config {
+	var cfg bridge.Config
+	f, err := os.Open(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&cfg); err != nil {
+		log.Fatalf("invalid config %q: %s", *configFile, err)
+	}
+	return cfg
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags]\n\nTELEX_URL must be set to the telex URL.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	url := os.Getenv("TELEX_URL")
+	if url == "" || *configFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	client, err := minitel.New(url)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid telex URL: %q", err)
+		os.Exit(1)
+	}
+
+	var store minitel.IncidentStore = minitel.NewMemoryIncidentStore()
+	if *stateFile != "" {
+		store = minitel.NewFileIncidentStore(*stateFile)
+	}
+
+	b, err := bridge.New(client, store, getConfig())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Printf("accepting Alertmanager webhooks on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, b))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/heroku/minitel-go"
+	"github.com/heroku/minitel-go/gateway"
+)
+
+var tokensFile = flag.String("tokens", "", "Path to a JSON object mapping caller names to their tokens")
+var listen = flag.String("listen", ":8080", "Address to accept requests on")
+var workers = flag.Int("workers", 4, "Number of requests relayed to telex at once")
+var queueSize = flag.Int("queue", 100, "Number of requests waiting to be relayed before new ones are rejected")
+var attempts = flag.Int("attempts", 3, "Maximum attempts at relaying a request")
+var backoff = flag.Duration("backoff", 500*time.Millisecond, "Wait before the first retry, doubled for each one after it")
+var maxBody = flag.Int64("max-body", 1<<20, "Largest request body accepted, in bytes")
+
+func getCallers() map[string]string {
+	var callers map[string]string
+	f, err := os.Open(*tokensFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	if err := dec.Decode(&callers); err != nil {
+		log.Fatalf("invalid tokens %q: %s", *tokensFile, err)
+	}
+	return callers
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags]\n\nTELEX_URL must be set to the telex URL.\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	url := os.Getenv("TELEX_URL")
+	if url == "" || *tokensFile == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	client, err := minitel.New(url)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid telex URL: %q", err)
+		os.Exit(1)
+	}
+
+	g := gateway.New(client, gateway.Config{
+		Callers:     getCallers(),
+		Workers:     *workers,
+		QueueSize:   *queueSize,
+		MaxAttempts: *attempts,
+		Backoff:     *backoff,
+		MaxBodySize: *maxBody,
+	})
+	srv := &http.Server{Addr: *listen, Handler: g}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		// Wait for queued requests to be relayed and answered.
+		if err := srv.Shutdown(context.Background()); err != nil {
+			log.Print(err)
+		}
+		g.Close()
+	}()
+
+	log.Printf("accepting notifications on %s", *listen)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+	<-done
+}
@@ -14,6 +14,7 @@ var targetID = flag.String("target", "", "Target UUID")
 var followupID = flag.String("followup", "", "followup")
 var argType = flag.String("type", "", "Target Type (app, user)")
 var title = flag.String("title", "Default Title", "Title")
+var dryRun = flag.Bool("dry-run", false, "Print the request instead of sending it")
 
 func getBody() string {
 	body, err := ioutil.ReadAll(os.Stdin)
@@ -36,7 +37,12 @@ func getClient() *minitel.Client {
 		os.Exit(1)
 	}
 
-	client, err := minitel.New(url)
+	var opts []minitel.Option
+	if *dryRun {
+		opts = append(opts, minitel.WithDryRun(minitel.PrintDryRun(os.Stdout)))
+	}
+
+	client, err := minitel.New(url, opts...)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "invalid telex URL: %q", err)
 		os.Exit(1)
@@ -72,6 +78,10 @@ func post() {
 		fmt.Fprintf(os.Stderr, "received the following error: %q", err)
 		os.Exit(1)
 	}
+	if res.DryRun {
+		fmt.Printf("Dry run, message not posted.")
+		return
+	}
 	fmt.Printf("Posted message. ID=%q", res.ID)
 }
 
@@ -81,6 +91,10 @@ func followup() {
 		fmt.Fprintf(os.Stderr, "received the following error: %q", err)
 		os.Exit(1)
 	}
+	if res.DryRun {
+		fmt.Printf("Dry run, followup message to %q not posted.", *followupID)
+		return
+	}
 	fmt.Printf("Posted followup message to %q. ID=%q", *followupID, res.ID)
 	return
 }
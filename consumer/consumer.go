@@ -0,0 +1,159 @@
+// Package consumer implements the consumer side of the Telex API: listing the
+// notifications sent to a user, fetching a single notification along with its
+// followups and marking notifications read or unread.
+//
+// Requests are made through a minitel.Client, so the credentials in the Telex
+// URL given to minitel.New identify the user whose notifications are used.
+package consumer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	minitel "github.com/heroku/minitel-go"
+)
+
+// Notification as seen by the user it was sent to.
+type Notification struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+
+	Action    minitel.Action `json:"action"`
+	Read      bool           `json:"read"`
+	CreatedAt time.Time      `json:"created_at"`
+
+	// Followups are only populated by Client.Get.
+	Followups []Followup `json:"followup,omitempty"`
+}
+
+// Followup text added to a Notification after it was created.
+type Followup struct {
+	ID        string    `json:"id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ListOptions control which page of notifications List returns.
+type ListOptions struct {
+	// Max number of notifications to return. Zero lets Telex decide.
+	Max int
+
+	// Range of the page to fetch, usually the NextRange of a previous Page.
+	// Takes precedence over Max when set.
+	Range string
+}
+
+// Page of notifications returned by List.
+type Page struct {
+	Notifications []Notification
+
+	// NextRange is set when there are more notifications to fetch. Pass it as
+	// ListOptions.Range to get the next page.
+	NextRange string
+}
+
+// Client for the consumer side of Telex.
+type Client struct {
+	c *minitel.Client
+}
+
+// New consumer Client using the transport and credentials of c.
+func New(c *minitel.Client) *Client {
+	return &Client{c: c}
+}
+
+// List a page of the user's notifications.
+func (c *Client) List(opts ListOptions) (page Page, err error) {
+	req, err := c.c.NewRequest(http.MethodGet, "/user/notifications", nil)
+	if err != nil {
+		return page, err
+	}
+	switch {
+	case opts.Range != "":
+		req.Header.Set("Range", opts.Range)
+	case opts.Max > 0:
+		req.Header.Set("Range", fmt.Sprintf("id ..; max=%d", opts.Max))
+	}
+
+	resp, err := c.do(req, &page.Notifications, http.StatusOK, http.StatusPartialContent)
+	if err != nil {
+		return page, err
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		page.NextRange = resp.Header.Get("Next-Range")
+	}
+	return page, nil
+}
+
+// Get the notification identified by id, including its followups.
+func (c *Client) Get(id string) (n Notification, err error) {
+	req, err := c.c.NewRequest(http.MethodGet, "/user/notifications/"+url.PathEscape(id), nil)
+	if err != nil {
+		return n, err
+	}
+	_, err = c.do(req, &n, http.StatusOK)
+	return n, err
+}
+
+// MarkRead marks the notification identified by id as read.
+func (c *Client) MarkRead(id string) error {
+	return c.mark(id, true)
+}
+
+// MarkUnread marks the notification identified by id as unread.
+func (c *Client) MarkUnread(id string) error {
+	return c.mark(id, false)
+}
+
+func (c *Client) mark(id string, read bool) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(map[string]bool{"read": read}); err != nil {
+		return err
+	}
+
+	req, err := c.c.NewRequest(http.MethodPatch, "/user/notifications/"+url.PathEscape(id), &buf)
+	if err != nil {
+		return err
+	}
+	_, err = c.do(req, nil, http.StatusOK)
+	return err
+}
+
+// do sends req, checks the response has one of the expected status codes and
+// decodes the body into v unless v is nil.
+func (c *Client) do(req *http.Request, v interface{}, expected ...int) (*http.Response, error) {
+	resp, err := c.c.Client.Do(req)
+	if err != nil {
+		return nil, c.c.Redact(err)
+	}
+	defer resp.Body.Close()
+
+	if !statusIn(resp.StatusCode, expected) {
+		return resp, &minitel.StatusError{Expected: expected[0], Got: resp.StatusCode}
+	}
+
+	if v == nil {
+		return resp, nil
+	}
+	dec := json.NewDecoder(resp.Body)
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return resp, err
+	}
+	return resp, nil
+}
+
+func statusIn(code int, codes []int) bool {
+	for _, c := range codes {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
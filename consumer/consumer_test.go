@@ -0,0 +1,108 @@
+package consumer
+
+import (
+	"testing"
+
+	minitel "github.com/heroku/minitel-go"
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func newTestClient(t *testing.T, ts *miniteltest.TestServer) *Client {
+	c, err := minitel.New(ts.URL)
+	if err != nil {
+		t.Fatalf("unable to construct minitel client from TestServer.URL (%q): %s", ts.URL, err)
+	}
+	return New(c)
+}
+
+func TestList(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+
+	var ids []string
+	for _, title := range []string{"one", "two", "three"} {
+		ids = append(ids, ts.AddUserNotification(miniteltest.UserNotification{Title: title}))
+	}
+	c := newTestClient(t, ts)
+
+	var got []string
+	opts := ListOptions{Max: 2}
+	for pages := 0; ; pages++ {
+		if pages > len(ids) {
+			t.Fatal("too many pages returned")
+		}
+		page, err := c.List(opts)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		for _, n := range page.Notifications {
+			got = append(got, n.ID)
+		}
+		if page.NextRange == "" {
+			break
+		}
+		opts = ListOptions{Range: page.NextRange}
+	}
+
+	if len(got) != len(ids) {
+		t.Fatalf("expected %d notifications, got %d", len(ids), len(got))
+	}
+	for i := range ids {
+		if got[i] != ids[i] {
+			t.Errorf("expected notification %d to be %q, got %q", i, ids[i], got[i])
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+
+	id := ts.AddUserNotification(miniteltest.UserNotification{
+		Title:     "Hello",
+		Body:      "DB on fire!",
+		Action:    miniteltest.Action{Label: "View", URL: "https://example.com"},
+		Followups: []miniteltest.UserFollowup{{ID: "f1", Body: "Still on fire"}},
+	})
+	c := newTestClient(t, ts)
+
+	n, err := c.Get(id)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if n.ID != id || n.Title != "Hello" || n.Action.URL != "https://example.com" {
+		t.Errorf("unexpected notification: %+v", n)
+	}
+	if len(n.Followups) != 1 || n.Followups[0].Body != "Still on fire" {
+		t.Errorf("unexpected followups: %+v", n.Followups)
+	}
+
+	if _, err := c.Get("missing"); err == nil {
+		t.Error("expected error for missing notification but was nil")
+	}
+}
+
+func TestMark(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+
+	id := ts.AddUserNotification(miniteltest.UserNotification{Title: "Hello"})
+	c := newTestClient(t, ts)
+
+	for _, read := range []bool{true, false} {
+		mark := c.MarkUnread
+		if read {
+			mark = c.MarkRead
+		}
+		if err := mark(id); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		n, ok := ts.UserNotification(id)
+		if !ok {
+			t.Fatal("notification disappeared from TestServer")
+		}
+		if n.Read != read {
+			t.Errorf("expected Read to be %t, got %t", read, n.Read)
+		}
+	}
+}
@@ -1,14 +1,18 @@
 package minitel
 
-import "context"
+import (
+	"context"
+	"net/http"
+)
 
 type minitelKey int
 
 var key minitelKey
 
-// NewContext returns a new Context that includes the Client
-func NewContext(ctx context.Context, cli *Client) context.Context {
-	return context.WithValue(ctx, key, cli)
+// NewContext returns a new Context that includes the Notifier, usually a
+// Client.
+func NewContext(ctx context.Context, n Notifier) context.Context {
+	return context.WithValue(ctx, key, n)
 }
 
 // FromContext retrieves the Client value stored in ctx, if any.
@@ -16,3 +20,45 @@ func FromContext(ctx context.Context) (cli *Client, ok bool) {
 	cli, ok = ctx.Value(key).(*Client)
 	return
 }
+
+// NotifierFromContext retrieves the Notifier stored in ctx. If there is none a
+// NopNotifier is returned, so callers can always send.
+func NotifierFromContext(ctx context.Context) Notifier {
+	if n, ok := ctx.Value(key).(Notifier); ok {
+		return n
+	}
+	return NopNotifier{}
+}
+
+// MustFromContext retrieves the Notifier stored in ctx, panicking if there is
+// none.
+func MustFromContext(ctx context.Context) Notifier {
+	n, ok := ctx.Value(key).(Notifier)
+	if !ok {
+		panic("minitel: no Notifier in context")
+	}
+	return n
+}
+
+// Middleware returns net/http middleware that stores n in the context of every
+// request.
+func Middleware(n Notifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), n)))
+		})
+	}
+}
+
+// NopNotifier discards Notifications and Followups.
+type NopNotifier struct{}
+
+// Notify does nothing.
+func (NopNotifier) Notify(Notification) (Result, error) {
+	return Result{}, nil
+}
+
+// Followup does nothing.
+func (NopNotifier) Followup(id, text string) (Result, error) {
+	return Result{}, nil
+}
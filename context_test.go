@@ -0,0 +1,87 @@
+package minitel
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeNotifier records the Notifications and Followups it is asked to send,
+// failing them with err if set.
+type fakeNotifier struct {
+	mu        sync.Mutex
+	sent      []Notification
+	followups []string
+	err       error
+}
+
+func (f *fakeNotifier) Notify(n Notification) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, n)
+	if f.err != nil {
+		return Result{}, f.err
+	}
+	return Result{ID: "fake"}, nil
+}
+
+func (f *fakeNotifier) Followup(id, text string) (Result, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.followups = append(f.followups, text)
+	if f.err != nil {
+		return Result{}, f.err
+	}
+	return Result{ID: "fake"}, nil
+}
+
+func TestContext(t *testing.T) {
+	c, err := New("http://localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := NewContext(context.Background(), c)
+	if got, ok := FromContext(ctx); !ok || got != c {
+		t.Errorf("expected FromContext to return the Client, got %v, %t", got, ok)
+	}
+	if got := NotifierFromContext(ctx); got != Notifier(c) {
+		t.Errorf("expected NotifierFromContext to return the Client, got %v", got)
+	}
+
+	f := &fakeNotifier{}
+	ctx = NewContext(context.Background(), f)
+	if _, ok := FromContext(ctx); ok {
+		t.Error("expected FromContext not to find a Client when a fake is stored")
+	}
+	if got := MustFromContext(ctx); got != Notifier(f) {
+		t.Errorf("expected MustFromContext to return the fake, got %v", got)
+	}
+}
+
+func TestContextEmpty(t *testing.T) {
+	ctx := context.Background()
+	if _, ok := NotifierFromContext(ctx).(NopNotifier); !ok {
+		t.Error("expected NotifierFromContext to default to NopNotifier")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected MustFromContext to panic")
+		}
+	}()
+	MustFromContext(ctx)
+}
+
+func TestMiddleware(t *testing.T) {
+	f := &fakeNotifier{}
+	h := Middleware(f)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		NotifierFromContext(r.Context()).Notify(testNotification())
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if len(f.sent) != 1 {
+		t.Errorf("expected the handler to send through the injected Notifier, got %d", len(f.sent))
+	}
+}
@@ -0,0 +1,72 @@
+package minitel
+
+// Decorator modifies a Notification before the Client validates and sends it.
+// The Notification's Tags, Metadata and Localizations are shared with the
+// caller, so a Decorator changing them must copy them first.
+type Decorator func(*Notification) error
+
+// WithDecorators adds ds to the Decorators applied, in order, to every
+// Notification the Client sends. See Client.NotifyUndecorated to skip them.
+func WithDecorators(ds ...Decorator) Option {
+	return func(c *Client) error {
+		c.decorators = append(c.decorators, ds...)
+		return nil
+	}
+}
+
+// decorate n with the Client's Decorators.
+func (c *Client) decorate(n Notification) (Notification, error) {
+	for _, d := range c.decorators {
+		if err := d(&n); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// DefaultAction sets the Action of Notifications that don't have one.
+func DefaultAction(a Action) Decorator {
+	return func(n *Notification) error {
+		if n.Action.URL == "" {
+			n.Action = a
+		}
+		return nil
+	}
+}
+
+// TitlePrefix prefixes the Title, and that of every Localization.
+func TitlePrefix(prefix string) Decorator {
+	return func(n *Notification) error {
+		n.Title = prefix + n.Title
+		n.Localizations = mapLocalizations(n.Localizations, func(l Localization) Localization {
+			l.Title = prefix + l.Title
+			return l
+		})
+		return nil
+	}
+}
+
+// BodyFooter appends footer to the Body, and that of every Localization,
+// separated by a blank line.
+func BodyFooter(footer string) Decorator {
+	return func(n *Notification) error {
+		n.Body += "\n\n" + footer
+		n.Localizations = mapLocalizations(n.Localizations, func(l Localization) Localization {
+			l.Body += "\n\n" + footer
+			return l
+		})
+		return nil
+	}
+}
+
+// mapLocalizations returns a copy of ls with f applied to each.
+func mapLocalizations(ls map[string]Localization, f func(Localization) Localization) map[string]Localization {
+	if ls == nil {
+		return nil
+	}
+	mapped := make(map[string]Localization, len(ls))
+	for tag, l := range ls {
+		mapped[tag] = f(l)
+	}
+	return mapped
+}
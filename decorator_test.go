@@ -0,0 +1,80 @@
+package minitel
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestDecorators(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+	ts.ExpectNotify(nil)
+
+	dashboard := Action{Label: "View in Dashboard", URL: "https://dashboard.heroku.com"}
+	c, err := New(ts.URL, WithDecorators(
+		DefaultAction(dashboard),
+		TitlePrefix("[billing] "),
+		BodyFooter("-- The Billing Team"),
+		func(n *Notification) error {
+			n.Category = "billing"
+			return nil
+		},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := testNotification()
+	n.Localizations = map[string]Localization{"de": {Title: "Hallo", Body: "Welt"}}
+	n.FallbackLocale = "de"
+	if _, err := c.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.NotifyUndecorated(n); err != nil {
+		t.Fatal(err)
+	}
+	if finished := ts.ExpectDone(time.Second); !finished {
+		t.Error("expected no pending expectations, but some still exist")
+	}
+
+	got := ts.Notifications()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(got))
+	}
+	if d := got[0]; d.Title != "[billing] Hallo" || d.Body != "Welt\n\n-- The Billing Team" || d.Action.URL != dashboard.URL || d.Category != "billing" {
+		t.Errorf("unexpected decorated notification: %+v", d)
+	}
+	if d := got[1]; d.Title != "Hallo" || d.Action.URL != "" || d.Category != "" {
+		t.Errorf("expected opted out notification to be sent as given, got %+v", d)
+	}
+	if n.Localizations["de"].Title != "Hallo" {
+		t.Errorf("expected the caller's localizations to be left alone, got %+v", n.Localizations)
+	}
+}
+
+func TestDecoratorError(t *testing.T) {
+	errBoom := errors.New("boom")
+	c, err := New("http://localhost", WithDecorators(func(*Notification) error { return errBoom }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Notify(testNotification()); err != errBoom {
+		t.Errorf("expected the decorator's error, got %v", err)
+	}
+
+	// Decorators run before validation, so can fill in required fields.
+	c, err = New("http://localhost", WithDryRun(func(DryRunRequest) {}), WithDecorators(func(n *Notification) error {
+		n.Target = Target{Type: App, ID: "93f90f07-bbe3-433d-806d-2d01bc5ae1f2"}
+		return nil
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Notify(Notification{Title: "Untargeted"}); err != nil {
+		t.Errorf("expected decorated notification to validate, got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package minitel
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// defaultDedupSize is the number of keys remembered by the store used when
+// none is configured with WithDedupStore.
+const defaultDedupSize = 1024
+
+// DedupEntry records a sent Notification for a Deduper.
+type DedupEntry struct {
+	Result Result
+	Sent   time.Time
+}
+
+// DedupStore remembers recently sent Notifications by key. A Deduper
+// serialises its calls to the store.
+type DedupStore interface {
+	Get(key string) (DedupEntry, bool)
+	Put(key string, e DedupEntry)
+}
+
+// DedupOption configures a Deduper.
+type DedupOption func(*Deduper)
+
+// WithDedupStore sets the store used to remember sent Notifications.
+func WithDedupStore(s DedupStore) DedupOption {
+	return func(d *Deduper) {
+		d.store = s
+	}
+}
+
+// WithDedupClock sets the Clock used to decide whether a Notification was sent
+// within the window.
+func WithDedupClock(c Clock) DedupOption {
+	return func(d *Deduper) {
+		d.clock = c
+	}
+}
+
+// Deduper suppresses Notifications that duplicate one sent within a window.
+// Suppressed calls to Notify return the Result of the original Notification.
+// Followups are passed through unchanged.
+type Deduper struct {
+	Notifier
+
+	window time.Duration
+	store  DedupStore
+	clock  Clock
+
+	mu       sync.Mutex
+	inflight map[string]*dedupCall
+}
+
+// dedupCall is a Notify in progress that duplicates wait on.
+type dedupCall struct {
+	done   chan struct{}
+	result Result
+	err    error
+}
+
+// NewDeduper returns a Deduper sending through n that suppresses duplicates
+// within window. Unless configured otherwise the last 1024 keys are
+// remembered.
+func NewDeduper(n Notifier, window time.Duration, opts ...DedupOption) *Deduper {
+	d := &Deduper{
+		Notifier: n,
+		window:   window,
+		clock:    SystemClock,
+		inflight: make(map[string]*dedupCall),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.store == nil {
+		d.store = NewLRUDedupStore(defaultDedupSize)
+	}
+	return d
+}
+
+// DedupKey returns the key Notify uses for n, a hash of its Target, Title and
+// Body.
+func DedupKey(n Notification) string {
+	h := sha256.New()
+	for _, s := range []string{string(n.Target.Type), n.Target.ID, n.Title, n.Body} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Notify sends n unless a Notification with the same DedupKey was sent within
+// the window.
+func (d *Deduper) Notify(n Notification) (Result, error) {
+	return d.NotifyKey(DedupKey(n), n)
+}
+
+// NotifyKey sends n unless a Notification with the same key was sent within
+// the window. Use it when the caller knows better than DedupKey what makes
+// Notifications duplicates.
+func (d *Deduper) NotifyKey(key string, n Notification) (Result, error) {
+	d.mu.Lock()
+	if e, ok := d.store.Get(key); ok && d.clock.Now().Sub(e.Sent) < d.window {
+		d.mu.Unlock()
+		return e.Result, nil
+	}
+	if call, ok := d.inflight[key]; ok {
+		d.mu.Unlock()
+		<-call.done
+		return call.result, call.err
+	}
+	call := &dedupCall{done: make(chan struct{})}
+	d.inflight[key] = call
+	d.mu.Unlock()
+
+	call.result, call.err = d.Notifier.Notify(n)
+
+	d.mu.Lock()
+	delete(d.inflight, key)
+	// Failures aren't remembered so that a retry is sent.
+	if call.err == nil {
+		d.store.Put(key, DedupEntry{Result: call.result, Sent: d.clock.Now()})
+	}
+	d.mu.Unlock()
+	close(call.done)
+
+	return call.result, call.err
+}
+
+// LRUDedupStore is an in memory DedupStore that forgets the least recently
+// used key once it holds its maximum number of keys.
+type LRUDedupStore struct {
+	max   int
+	order *list.List
+	items map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry DedupEntry
+}
+
+// NewLRUDedupStore returns a LRUDedupStore holding at most max keys.
+func NewLRUDedupStore(max int) *LRUDedupStore {
+	return &LRUDedupStore{
+		max:   max,
+		order: list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get the entry for key.
+func (s *LRUDedupStore) Get(key string) (DedupEntry, bool) {
+	el, ok := s.items[key]
+	if !ok {
+		return DedupEntry{}, false
+	}
+	s.order.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+// Put the entry for key, evicting the least recently used key if needed.
+func (s *LRUDedupStore) Put(key string, e DedupEntry) {
+	if el, ok := s.items[key]; ok {
+		el.Value.(*lruItem).entry = e
+		s.order.MoveToFront(el)
+		return
+	}
+	s.items[key] = s.order.PushFront(&lruItem{key: key, entry: e})
+	if s.order.Len() > s.max {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+// Len returns the number of keys held.
+func (s *LRUDedupStore) Len() int {
+	return s.order.Len()
+}
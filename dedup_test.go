@@ -0,0 +1,127 @@
+package minitel
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestDeduper(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(
+		miniteltest.GenerateHTTPResponse(t, "first", http.StatusCreated),
+		miniteltest.GenerateHTTPResponse(t, "second", http.StatusCreated),
+		miniteltest.GenerateHTTPResponse(t, "third", http.StatusCreated),
+	)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := miniteltest.NewClock(time.Now())
+	d := NewDeduper(c, time.Minute, WithDedupClock(clock))
+
+	n := testNotification()
+	other := n
+	other.Body = "DB still on fire!"
+
+	for _, step := range []struct {
+		name    string
+		advance time.Duration
+		n       Notification
+		wantID  string
+	}{
+		{name: "first send", n: n, wantID: "first"},
+		{name: "duplicate suppressed", advance: 30 * time.Second, n: n, wantID: "first"},
+		{name: "different body sent", n: other, wantID: "second"},
+		{name: "window expired", advance: 31 * time.Second, n: n, wantID: "third"},
+	} {
+		clock.Advance(step.advance)
+		res, err := d.Notify(step.n)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", step.name, err)
+		}
+		if res.ID != step.wantID {
+			t.Errorf("%s: expected ID %q, got %q", step.name, step.wantID, res.ID)
+		}
+	}
+
+	if finished := ts.ExpectDone(time.Second); !finished {
+		t.Error("expected no pending expectations, but some still exist")
+	}
+}
+
+func TestDeduperNotifyKey(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(miniteltest.GenerateHTTPResponse(t, "first", http.StatusCreated))
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDeduper(c, time.Minute)
+
+	n := testNotification()
+	if _, err := d.NotifyKey("check-1", n); err != nil {
+		t.Fatal(err)
+	}
+	n.Body = "Something else entirely"
+	res, err := d.NotifyKey("check-1", n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "first" {
+		t.Errorf("expected duplicate key to return the original result, got %q", res.ID)
+	}
+}
+
+func TestDeduperFailureNotRemembered(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(
+		miniteltest.GenerateHTTPResponse(t, "", http.StatusInternalServerError),
+		miniteltest.GenerateHTTPResponse(t, "retried", http.StatusCreated),
+	)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDeduper(c, time.Minute)
+
+	n := testNotification()
+	if _, err := d.Notify(n); err == nil {
+		t.Fatal("expected error but was nil")
+	}
+	res, err := d.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "retried" {
+		t.Errorf("expected retry to be sent, got %q", res.ID)
+	}
+}
+
+func TestLRUDedupStore(t *testing.T) {
+	s := NewLRUDedupStore(2)
+	s.Put("a", DedupEntry{Result: Result{ID: "a"}})
+	s.Put("b", DedupEntry{Result: Result{ID: "b"}})
+	s.Get("a")
+	s.Put("c", DedupEntry{Result: Result{ID: "c"}})
+
+	if s.Len() != 2 {
+		t.Fatalf("expected 2 keys, got %d", s.Len())
+	}
+	if _, ok := s.Get("b"); ok {
+		t.Error("expected least recently used key to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if e, ok := s.Get(key); !ok || e.Result.ID != key {
+			t.Errorf("expected key %q to be kept, got %+v, %t", key, e, ok)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package minitel
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errDigesterClosed = errors.New("minitel: Digester is closed")
+
+// Renderer merges the Notifications buffered for a Target into a single
+// Notification.
+type Renderer func(ns []Notification) Notification
+
+// DefaultRenderer returns a single Notification unchanged. Otherwise the
+// digest lists every Notification's Title and Body as a bullet, uses the first
+// Action specified and the highest Severity.
+func DefaultRenderer(ns []Notification) Notification {
+	if len(ns) == 1 {
+		return ns[0]
+	}
+
+	d := Notification{
+		Title:  fmt.Sprintf("%s (and %d more)", ns[0].Title, len(ns)-1),
+		Target: ns[0].Target,
+	}
+	var body strings.Builder
+	for _, n := range ns {
+		body.WriteString("- " + n.Title)
+		if n.Body != "" {
+			body.WriteString(": " + n.Body)
+		}
+		body.WriteString("\n")
+
+		if d.Action == (Action{}) {
+			d.Action = n.Action
+		}
+		if severityRank(n.Severity) > severityRank(d.Severity) {
+			d.Severity = n.Severity
+		}
+	}
+	d.Body = body.String()
+	return d
+}
+
+func severityRank(s Severity) int {
+	switch s {
+	case Info:
+		return 1
+	case Warning:
+		return 2
+	case Critical:
+		return 3
+	}
+	return 0
+}
+
+// DigestOption configures a Digester.
+type DigestOption func(*Digester)
+
+// WithDigestRenderer sets the Renderer used to merge buffered Notifications.
+func WithDigestRenderer(r Renderer) DigestOption {
+	return func(d *Digester) {
+		d.render = r
+	}
+}
+
+// WithDigestMaxCount flushes a Target's digest as soon as it holds n
+// Notifications instead of waiting for the window to pass.
+func WithDigestMaxCount(n int) DigestOption {
+	return func(d *Digester) {
+		d.maxCount = n
+	}
+}
+
+// WithDigestClock sets the Clock used to time windows.
+func WithDigestClock(c Clock) DigestOption {
+	return func(d *Digester) {
+		d.clock = c
+	}
+}
+
+// WithDigestErrorHandler sets a func called with the errors of digests sent
+// when a window passes, as there is no caller to return them to.
+func WithDigestErrorHandler(f func(error)) DigestOption {
+	return func(d *Digester) {
+		d.onError = f
+	}
+}
+
+// Digester buffers Notifications per Target and sends them as a single digest
+// once the window that started with the first of them has passed. Followups
+// are passed through unchanged.
+type Digester struct {
+	Notifier
+
+	window   time.Duration
+	maxCount int
+	render   Renderer
+	clock    Clock
+	onError  func(error)
+
+	mu      sync.Mutex
+	buffers map[Target]*digestBuffer
+	closed  bool
+}
+
+type digestBuffer struct {
+	ns   []Notification
+	stop func() bool
+}
+
+// NewDigester returns a Digester sending digests through n for the
+// Notifications received within window of each other.
+func NewDigester(n Notifier, window time.Duration, opts ...DigestOption) *Digester {
+	d := &Digester{
+		Notifier: n,
+		window:   window,
+		render:   DefaultRenderer,
+		clock:    SystemClock,
+		onError:  func(error) {},
+		buffers:  make(map[Target]*digestBuffer),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Notify buffers n for its Target. The returned Result is empty unless n
+// caused the digest to be sent because the maximum count was reached.
+func (d *Digester) Notify(n Notification) (Result, error) {
+	if err := n.Validate(); err != nil {
+		return Result{}, err
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return Result{}, errDigesterClosed
+	}
+	buf, ok := d.buffers[n.Target]
+	if !ok {
+		buf = &digestBuffer{}
+		target := n.Target
+		buf.stop = d.clock.AfterFunc(d.window, func() {
+			if _, err := d.flush(target, buf); err != nil {
+				d.onError(err)
+			}
+		})
+		d.buffers[n.Target] = buf
+	}
+	buf.ns = append(buf.ns, n)
+	full := d.maxCount > 0 && len(buf.ns) >= d.maxCount
+	d.mu.Unlock()
+
+	if !full {
+		return Result{}, nil
+	}
+	return d.flush(n.Target, buf)
+}
+
+// flush sends the digest of buf unless it has already been sent.
+func (d *Digester) flush(target Target, buf *digestBuffer) (Result, error) {
+	d.mu.Lock()
+	if d.buffers[target] != buf {
+		d.mu.Unlock()
+		return Result{}, nil
+	}
+	delete(d.buffers, target)
+	buf.stop()
+	d.mu.Unlock()
+
+	return d.Notifier.Notify(d.render(buf.ns))
+}
+
+// Close sends every buffered digest and stops accepting Notifications. The
+// first error encountered is returned.
+func (d *Digester) Close() error {
+	d.mu.Lock()
+	d.closed = true
+	pending := make(map[Target]*digestBuffer, len(d.buffers))
+	for target, buf := range d.buffers {
+		pending[target] = buf
+	}
+	d.mu.Unlock()
+
+	var first error
+	for target, buf := range pending {
+		if _, err := d.flush(target, buf); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
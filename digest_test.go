@@ -0,0 +1,133 @@
+package minitel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestDigester(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := miniteltest.NewClock(time.Now())
+	d := NewDigester(c, time.Minute, WithDigestClock(clock), WithDigestErrorHandler(func(err error) {
+		t.Error("unexpected error: ", err)
+	}))
+
+	for i, title := range []string{"Release v1 failed", "Release v2 failed", "Release v3 failed"} {
+		n := testNotification()
+		n.Title = title
+		n.Body = "build error"
+		if i == 1 {
+			n.Action = Action{Label: "View", URL: "https://example.com/v2"}
+			n.Severity = Warning
+		}
+		res, err := d.Notify(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ID != "" {
+			t.Errorf("expected buffered notification to have no ID, got %q", res.ID)
+		}
+		clock.Advance(10 * time.Second)
+	}
+
+	if got := len(ts.Notifications()); got != 0 {
+		t.Fatalf("expected nothing sent before the window passed, got %d", got)
+	}
+	clock.Advance(time.Minute)
+
+	got := ts.Notifications()
+	if len(got) != 1 {
+		t.Fatalf("expected a single digest, got %d notifications", len(got))
+	}
+	want := "- Release v1 failed: build error\n- Release v2 failed: build error\n- Release v3 failed: build error\n"
+	if got[0].Body != want {
+		t.Errorf("expected body %q, got %q", want, got[0].Body)
+	}
+	if got[0].Title != "Release v1 failed (and 2 more)" {
+		t.Errorf("unexpected title %q", got[0].Title)
+	}
+	if got[0].Action.URL != "https://example.com/v2" || got[0].Severity != "warning" {
+		t.Errorf("expected first action and highest severity, got %+v", got[0])
+	}
+}
+
+func TestDigesterMaxCount(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := miniteltest.NewClock(time.Now())
+	d := NewDigester(c, time.Minute, WithDigestClock(clock), WithDigestMaxCount(2))
+
+	ns := testNotifications(2)
+	if _, err := d.Notify(ns[0]); err != nil {
+		t.Fatal(err)
+	}
+	res, err := d.Notify(ns[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID == "" {
+		t.Error("expected the digest to be sent once the max count was reached")
+	}
+
+	// The window's timer must not send the digest a second time.
+	clock.Advance(time.Minute)
+	if got := len(ts.Notifications()); got != 1 {
+		t.Errorf("expected 1 notification, got %d", got)
+	}
+}
+
+func TestDigesterClose(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil, nil)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := NewDigester(c, time.Hour, WithDigestRenderer(func(ns []Notification) Notification {
+		n := ns[0]
+		n.Title = "custom"
+		return n
+	}))
+
+	ns := testNotifications(2)
+	ns[1].Target.ID = "bc31ed62-0204-40e5-86cf-b25a001b20db"
+	for _, n := range ns {
+		if _, err := d.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got := ts.Notifications()
+	if len(got) != 2 {
+		t.Fatalf("expected a digest per target, got %d notifications", len(got))
+	}
+	for _, n := range got {
+		if n.Title != "custom" {
+			t.Errorf("expected custom renderer to be used, got %q", n.Title)
+		}
+	}
+
+	if _, err := d.Notify(ns[0]); err != errDigesterClosed {
+		t.Errorf("expected errDigesterClosed, got %v", err)
+	}
+}
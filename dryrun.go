@@ -0,0 +1,83 @@
+package minitel
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DryRunRequest is the HTTP request a Client in dry run mode would have sent.
+// The Authorization header is redacted.
+type DryRunRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   string
+}
+
+// String renders the request much as it would appear on the wire.
+func (r DryRunRequest) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", r.Method, r.URL)
+
+	keys := make([]string, 0, len(r.Header))
+	for k := range r.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range r.Header[k] {
+			fmt.Fprintf(&b, "%s: %s\n", k, v)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%s", r.Body)
+	if !strings.HasSuffix(r.Body, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// WithDryRun stops the Client sending Notifications and Followups. They are
+// still validated and encoded, then the request that would have been sent is
+// passed to f. The Results returned are empty apart from DryRun being set.
+func WithDryRun(f func(DryRunRequest)) Option {
+	return func(c *Client) error {
+		c.dryRun = f
+		return nil
+	}
+}
+
+// PrintDryRun returns a func for WithDryRun that writes each request to w.
+func PrintDryRun(w io.Writer) func(DryRunRequest) {
+	return func(r DryRunRequest) {
+		fmt.Fprintln(w, r)
+	}
+}
+
+// dryRunRequest passes the rendered req to the dry run func.
+func (c *Client) dryRunRequest(req *http.Request) error {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return err
+		}
+		req.Body.Close()
+	}
+
+	header := req.Header.Clone()
+	if header.Get("Authorization") != "" {
+		header.Set("Authorization", "Basic "+redacted)
+	}
+	c.dryRun(DryRunRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: header,
+		Body:   string(body),
+	})
+	return nil
+}
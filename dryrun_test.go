@@ -0,0 +1,86 @@
+package minitel
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestDryRun(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+
+	var reqs []DryRunRequest
+	c, err := New(strings.Replace(ts.URL, "http://", "http://user:"+secret+"@", 1), WithDryRun(func(r DryRunRequest) {
+		reqs = append(reqs, r)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.Notify(testNotification())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.DryRun || res.ID != "" {
+		t.Errorf("expected a dry run result, got %+v", res)
+	}
+	if _, err := c.Followup("some-id", "followup text"); err != nil {
+		t.Fatal(err)
+	}
+	results, err := c.NotifyBatch(testNotifications(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if !r.DryRun {
+			t.Errorf("expected a dry run result, got %+v", r)
+		}
+	}
+
+	if _, err := c.Notify(Notification{}); err != errNoID {
+		t.Errorf("expected dry run to validate, got %v", err)
+	}
+
+	if got := len(ts.Notifications()); got != 0 {
+		t.Errorf("expected nothing sent, got %d notifications", got)
+	}
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 dry run requests, got %d", len(reqs))
+	}
+	if reqs[0].Method != "POST" || reqs[0].URL != ts.URL+"/producer/messages" {
+		t.Errorf("unexpected request: %s %s", reqs[0].Method, reqs[0].URL)
+	}
+	if !strings.Contains(reqs[0].Body, `"title":"Hello"`) {
+		t.Errorf("expected encoded notification in body, got %q", reqs[0].Body)
+	}
+	if !strings.HasSuffix(reqs[1].URL, "/producer/messages/some-id/followups") {
+		t.Errorf("unexpected followup URL: %s", reqs[1].URL)
+	}
+	for _, r := range reqs {
+		if s := r.String(); strings.Contains(s, secret) || !strings.Contains(s, "Authorization: Basic xxxxx") {
+			t.Errorf("expected redacted Authorization header, got:\n%s", s)
+		}
+	}
+}
+
+func TestPrintDryRun(t *testing.T) {
+	var buf bytes.Buffer
+	c, err := New("https://telex.example.com", WithDryRun(PrintDryRun(&buf)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Followup("some-id", "followup text"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "POST https://telex.example.com/producer/messages/some-id/followups\n" +
+		"Content-Type: application/json\n" +
+		"\n" +
+		"{\"body\":\"followup text\"}\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected:\n%q\ngot:\n%q", want, got)
+	}
+}
@@ -0,0 +1,206 @@
+package minitel
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errNoRecipient = errors.New("minitel: No email recipient for target")
+
+// emailHTML renders the HTML part of an email.
+var emailHTML = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body>
+<h1>{{ .Title }}</h1>
+{{ range .Paragraphs }}<p>{{ . }}</p>
+{{ end }}{{ with .Action }}<p><a href="{{ .URL }}">{{ .Label }}</a></p>
+{{ end }}</body>
+</html>
+`))
+
+// RecipientResolver returns the email address Notifications for a Target are
+// sent to. An empty address means the Target can't be emailed.
+type RecipientResolver interface {
+	Recipient(Target) (string, error)
+}
+
+// RecipientResolverFunc is a func usable as a RecipientResolver.
+type RecipientResolverFunc func(Target) (string, error)
+
+// Recipient calls f(t).
+func (f RecipientResolverFunc) Recipient(t Target) (string, error) {
+	return f(t)
+}
+
+// EmailOption configures an EmailSink.
+type EmailOption func(*EmailSink)
+
+// WithEmailAuth sets the auth used with the SMTP server.
+func WithEmailAuth(auth smtp.Auth) EmailOption {
+	return func(s *EmailSink) {
+		s.auth = auth
+	}
+}
+
+// EmailSink sends Notifications as email through an SMTP server. Each is
+// rendered with a plain text and an HTML part, with the Action as a link. The
+// Result ID is the generated Message-ID.
+type EmailSink struct {
+	addr       string
+	from       string
+	recipients RecipientResolver
+	auth       smtp.Auth
+}
+
+// NewEmailSink returns an EmailSink sending through the SMTP server at addr,
+// from the address from, to the address recipients resolves for each Target.
+func NewEmailSink(addr, from string, recipients RecipientResolver, opts ...EmailOption) *EmailSink {
+	s := &EmailSink{addr: addr, from: from, recipients: recipients}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Notify emails n to the recipient of its Target.
+func (s *EmailSink) Notify(n Notification) (Result, error) {
+	if err := n.Validate(); err != nil {
+		return Result{}, err
+	}
+	to, err := s.recipients.Recipient(n.Target)
+	if err != nil {
+		return Result{}, err
+	}
+	if to == "" {
+		return Result{}, errNoRecipient
+	}
+
+	id := uuid.New().String()
+	msg, err := renderEmail(id, s.from, to, n)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{to}, msg); err != nil {
+		return Result{}, err
+	}
+	return Result{ID: id}, nil
+}
+
+// renderEmail renders n as a multipart/alternative MIME message.
+func renderEmail(id, from, to string, n Notification) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", to)
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Title))
+	fmt.Fprintf(&buf, "Message-ID: <%s@minitel>\r\n", id)
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	var plain strings.Builder
+	plain.WriteString(n.Body)
+	if n.Action.URL != "" {
+		fmt.Fprintf(&plain, "\n\n%s: %s", n.Action.Label, n.Action.URL)
+	}
+	if err := writeEmailPart(mw, "text/plain", []byte(plain.String())); err != nil {
+		return nil, err
+	}
+
+	data := struct {
+		Title      string
+		Paragraphs []string
+		Action     *Action
+	}{Title: n.Title}
+	for _, p := range strings.Split(n.Body, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			data.Paragraphs = append(data.Paragraphs, p)
+		}
+	}
+	if n.Action.URL != "" {
+		data.Action = &n.Action
+	}
+	var html bytes.Buffer
+	if err := emailHTML.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	if err := writeEmailPart(mw, "text/html", html.Bytes()); err != nil {
+		return nil, err
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeEmailPart(mw *multipart.Writer, contentType string, body []byte) error {
+	w, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qw := quotedprintable.NewWriter(w)
+	if _, err := qw.Write(body); err != nil {
+		return err
+	}
+	return qw.Close()
+}
+
+// Failover sends Notifications to a primary Sink, usually a Client, and to the
+// Sink of a fallback Route when the primary fails with an error that
+// IsRetryable and the Route matches the Notification.
+type Failover struct {
+	primary  Sink
+	fallback Route
+}
+
+// NewFailover returns a Failover from primary to fallback. For example, to
+// email critical user notifications when Telex is down:
+//
+//	NewFailover(client, Route{
+//		Name:        "email",
+//		Sink:        NewEmailSink(addr, from, recipients),
+//		Types:       []Type{User},
+//		MinSeverity: Critical,
+//	})
+func NewFailover(primary Sink, fallback Route) *Failover {
+	return &Failover{primary: primary, fallback: fallback}
+}
+
+// Notify sends n to the primary Sink, falling back if needed. If the fallback
+// fails too the error reports both failures and wraps the primary's. If the
+// primary is a Client with an Environment, the fallback is sent n as
+// restricted by it, and nothing if it would be dropped.
+func (f *Failover) Notify(n Notification) (Result, error) {
+	res, err := f.primary.Notify(n)
+	if err == nil || !IsRetryable(err) || !f.fallback.matches(n) {
+		return res, err
+	}
+	if e := sinkEnvironment(f.primary); e != nil {
+		var eerr error
+		if n, eerr = e.restrict(n, false); eerr != nil {
+			return res, err
+		}
+	}
+
+	res, ferr := f.fallback.Sink.Notify(n)
+	if ferr != nil {
+		return res, fmt.Errorf("%w; fallback %s: %s", err, f.fallback.Name, ferr)
+	}
+	return res, nil
+}
@@ -0,0 +1,177 @@
+package minitel
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+var recipients = RecipientResolverFunc(func(t Target) (string, error) {
+	if t.Type != User {
+		return "", nil
+	}
+	return "user@example.com", nil
+})
+
+func TestEmailSink(t *testing.T) {
+	ss := miniteltest.NewSMTPServer()
+	defer ss.Close()
+
+	n := testNotification()
+	n.Target.Type = User
+	n.Title = "Dyno crashed"
+	n.Body = "web.1 crashed.\n\nIt was <restarted>."
+	n.Action = Action{Label: "View app", URL: "https://dashboard.heroku.com/apps/example"}
+
+	res, err := NewEmailSink(ss.Addr, "telex@example.com", recipients).Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msgs := ss.Messages()
+	if len(msgs) != 1 {
+		t.Fatalf("expected one email, got %d", len(msgs))
+	}
+	if msgs[0].From != "telex@example.com" || len(msgs[0].To) != 1 || msgs[0].To[0] != "user@example.com" {
+		t.Errorf("unexpected envelope: %+v", msgs[0])
+	}
+
+	m, err := mail.ReadMessage(bytes.NewReader(msgs[0].Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Header.Get("Subject"); got != n.Title {
+		t.Errorf("expected subject %q, got %q", n.Title, got)
+	}
+	if got := m.Header.Get("Message-ID"); !strings.Contains(got, res.ID) {
+		t.Errorf("expected Message-ID to contain the Result ID %q, got %q", res.ID, got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("expected multipart/alternative, got %q: %v", mediaType, err)
+	}
+	parts := make(map[string]string)
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[ct] = string(b)
+	}
+
+	if plain := parts["text/plain"]; !strings.Contains(plain, "It was <restarted>.") || !strings.Contains(plain, "View app: "+n.Action.URL) {
+		t.Errorf("unexpected plain part: %q", plain)
+	}
+	html := parts["text/html"]
+	for _, want := range []string{
+		"<p>It was &lt;restarted&gt;.</p>",
+		`<a href="` + n.Action.URL + `">View app</a>`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected HTML part to contain %q, got %q", want, html)
+		}
+	}
+
+	n.Target.Type = App
+	if _, err := NewEmailSink(ss.Addr, "telex@example.com", recipients).Notify(n); err != errNoRecipient {
+		t.Errorf("expected errNoRecipient for an app, got %v", err)
+	}
+}
+
+func TestFailover(t *testing.T) {
+	ss := miniteltest.NewSMTPServer()
+	defer ss.Close()
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(
+		miniteltest.GenerateHTTPResponse(t, "", http.StatusServiceUnavailable),
+		miniteltest.GenerateHTTPResponse(t, "", http.StatusServiceUnavailable),
+		miniteltest.GenerateHTTPResponse(t, "", http.StatusUnprocessableEntity),
+	)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFailover(c, Route{
+		Name:        "email",
+		Sink:        NewEmailSink(ss.Addr, "telex@example.com", recipients),
+		Types:       []Type{User},
+		MinSeverity: Critical,
+	})
+
+	n := testNotification()
+	n.Target.Type = User
+	n.Severity = Critical
+	res, err := f.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID == "" || len(ss.Messages()) != 1 {
+		t.Errorf("expected the notification to be emailed, got %+v", res)
+	}
+
+	// Not critical, so Telex's error is returned.
+	n.Severity = Info
+	if _, err := f.Notify(n); !IsRetryable(err) {
+		t.Errorf("expected the retryable Telex error, got %v", err)
+	}
+	// Not retryable, so no fallback.
+	n.Severity = Critical
+	if _, err := f.Notify(n); err == nil || IsRetryable(err) {
+		t.Errorf("expected the Telex error, got %v", err)
+	}
+	if got := len(ss.Messages()); got != 1 {
+		t.Errorf("expected a single email, got %d", got)
+	}
+}
+
+func TestFailoverEnvironment(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(miniteltest.GenerateHTTPResponse(t, "", http.StatusServiceUnavailable))
+
+	sandbox := Target{Type: User, ID: userUUID}
+	c, err := New(ts.URL, WithEnvironment(Environment{
+		Name:         "staging",
+		Sandbox:      sandbox,
+		OnSuppressed: func(SuppressedSend) {},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fallback := &fakeNotifier{}
+	f := NewFailover(c, Route{Name: "fallback", Sink: fallback})
+
+	customer := testNotification()
+	if _, err := f.Notify(customer); err != nil {
+		t.Fatal(err)
+	}
+	if len(fallback.sent) != 1 || fallback.sent[0].Target != sandbox || fallback.sent[0].Title != "[staging] "+customer.Title {
+		t.Fatalf("expected the fallback to be sent the redirected notification, got %+v", fallback.sent)
+	}
+
+	// Without a Sandbox the customer's notification is dropped, so there is
+	// nothing to fall back with.
+	c.env.Sandbox = Target{}
+	if res, err := f.Notify(customer); err != nil || !res.Suppressed {
+		t.Errorf("expected the notification to be dropped, got %+v, %v", res, err)
+	}
+	if len(fallback.sent) != 1 {
+		t.Errorf("expected nothing more sent to the fallback, got %+v", fallback.sent)
+	}
+}
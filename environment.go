@@ -0,0 +1,176 @@
+package minitel
+
+import (
+	"errors"
+	"log"
+	"sync"
+)
+
+var (
+	// errSuppressed is returned by Client.prepare when the Environment drops
+	// a Notification.
+	errSuppressed = errors.New("minitel: Notification suppressed by environment")
+
+	errNoEnvironmentName = errors.New("minitel: Environment.Name must not be empty")
+)
+
+// environmentThreads is the number of message IDs an Environment remembers
+// Followups may be added to.
+const environmentThreads = 1024
+
+// Environment keeps a Client outside production from notifying real
+// customers. Only Notifications to Targets in Allow are sent as they are;
+// others are redirected to Sandbox or, if it is the zero Target, dropped.
+// Every Title and Followup is prefixed with the Name in brackets.
+//
+// Followups are only sent to messages the Client created while the
+// Environment was in effect, of which the most recent are remembered. Others
+// are dropped, as they may belong to a real customer's notification.
+type Environment struct {
+	Name    string
+	Allow   []Target
+	Sandbox Target
+
+	// OnSuppressed is called with every Notification redirected or dropped,
+	// and every Followup dropped. Defaults to logging it with the standard
+	// logger.
+	OnSuppressed func(SuppressedSend)
+
+	sent *sentMessages
+}
+
+// sentMessages holds the IDs of the messages created in an Environment, which
+// Followups are allowed to.
+type sentMessages struct {
+	mu  sync.Mutex
+	ids *LRUDedupStore
+}
+
+func (s *sentMessages) contains(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.ids.Get(id)
+	return ok
+}
+
+func (s *sentMessages) add(res Result) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids.Put(res.ID, DedupEntry{Result: res})
+}
+
+// SuppressedSend is a Notification the Environment didn't send to its Target.
+type SuppressedSend struct {
+	Environment  string
+	Notification Notification
+
+	// Redirected is set if the Notification was sent to the Sandbox instead,
+	// otherwise it was dropped.
+	Redirected bool
+
+	// FollowupTo is set to the message ID of a dropped Followup, whose text
+	// is the Notification's Body.
+	FollowupTo string
+}
+
+// WithEnvironment restricts the Notifications and Followups the Client sends
+// according to e. Name is required and Sandbox, if set, must be a valid
+// Target.
+func WithEnvironment(e Environment) Option {
+	return func(c *Client) error {
+		if e.Name == "" {
+			return errNoEnvironmentName
+		}
+		if e.Sandbox != (Target{}) {
+			if err := (Notification{Target: e.Sandbox}).Validate(); err != nil {
+				return err
+			}
+		}
+		if e.OnSuppressed == nil {
+			e.OnSuppressed = logSuppressed
+		}
+		e.sent = &sentMessages{ids: NewLRUDedupStore(environmentThreads)}
+		c.env = &e
+		return nil
+	}
+}
+
+func logSuppressed(s SuppressedSend) {
+	if s.FollowupTo != "" {
+		log.Printf("minitel: %s: dropped followup to message %s not sent in this environment", s.Environment, s.FollowupTo)
+		return
+	}
+	what := "dropped"
+	if s.Redirected {
+		what = "redirected to sandbox"
+	}
+	log.Printf("minitel: %s: %s notification %q to %s %s", s.Environment, what, s.Notification.Title, s.Notification.Target.Type, s.Notification.Target.ID)
+}
+
+// applyEnvironment redirects or drops n unless its Target is allowed, and
+// prefixes its Title.
+func (c *Client) applyEnvironment(n Notification) (Notification, error) {
+	if c.env == nil {
+		return n, nil
+	}
+	return c.env.restrict(n, true)
+}
+
+// restrict redirects or drops n unless its Target is allowed, reporting it if
+// report is set, and prefixes its Title.
+func (e *Environment) restrict(n Notification, report bool) (Notification, error) {
+	if !e.allows(n.Target) {
+		redirect := e.Sandbox != Target{}
+		if report {
+			e.OnSuppressed(SuppressedSend{Environment: e.Name, Notification: n, Redirected: redirect})
+		}
+		if !redirect {
+			return n, errSuppressed
+		}
+		n.Target = e.Sandbox
+	}
+	n.Title = "[" + e.Name + "] " + n.Title
+	return n, nil
+}
+
+// sinkEnvironment returns the Environment of s if it is a Client with one, so
+// that copies of Notifications sent elsewhere alongside it can be restricted
+// the same way.
+func sinkEnvironment(s Sink) *Environment {
+	if c, ok := s.(*Client); ok {
+		return c.env
+	}
+	return nil
+}
+
+// applyEnvironmentFollowup drops a Followup to a message not created in the
+// Environment, and prefixes its text.
+func (c *Client) applyEnvironmentFollowup(id, text string) (string, error) {
+	e := c.env
+	if e == nil {
+		return text, nil
+	}
+
+	if !e.sent.contains(id) {
+		e.OnSuppressed(SuppressedSend{Environment: e.Name, Notification: Notification{Body: text}, FollowupTo: id})
+		return text, errSuppressed
+	}
+	return "[" + e.Name + "] " + text, nil
+}
+
+// environmentSent remembers that Followups may be added to the message res
+// created.
+func (c *Client) environmentSent(res Result) {
+	if c.env != nil && res.ID != "" {
+		c.env.sent.add(res)
+	}
+}
+
+func (e *Environment) allows(t Target) bool {
+	for _, a := range e.Allow {
+		if a == t {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package minitel
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestEnvironment(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+	ts.ExpectNotify(nil)
+
+	allowed := Target{Type: App, ID: "93f90f07-bbe3-433d-806d-2d01bc5ae1f2"}
+	sandbox := Target{Type: User, ID: "bc31ed62-0204-40e5-86cf-b25a001b20db"}
+	var suppressed []SuppressedSend
+	c, err := New(ts.URL, WithEnvironment(Environment{
+		Name:         "staging",
+		Allow:        []Target{allowed},
+		Sandbox:      sandbox,
+		OnSuppressed: func(s SuppressedSend) { suppressed = append(suppressed, s) },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := testNotification()
+	n.Target = allowed
+	if _, err := c.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	customer := n
+	customer.Target.ID = "0b9a1d4c-5c4a-4a5e-9f3b-7c0a2d6e8f11"
+	if _, err := c.Notify(customer); err != nil {
+		t.Fatal(err)
+	}
+
+	if finished := ts.ExpectDone(time.Second); !finished {
+		t.Error("expected no pending expectations, but some still exist")
+	}
+	got := ts.Notifications()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(got))
+	}
+	if got[0].Title != "[staging] "+n.Title || got[0].Target.ID != allowed.ID {
+		t.Errorf("unexpected allowed notification: %+v", got[0])
+	}
+	if got[1].Target.ID != sandbox.ID || got[1].Target.Type != string(sandbox.Type) {
+		t.Errorf("expected notification to be redirected to the sandbox, got %+v", got[1].Target)
+	}
+	if len(suppressed) != 1 || !suppressed[0].Redirected || suppressed[0].Notification.Target != customer.Target {
+		t.Errorf("expected the redirect to be reported, got %+v", suppressed)
+	}
+}
+
+func TestEnvironmentDrop(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.DisableBatch()
+	ts.ExpectNotify(nil)
+
+	var suppressed []SuppressedSend
+	c, err := New(ts.URL, WithBatchSize(10), WithEnvironment(Environment{
+		Name:         "review",
+		Allow:        []Target{testNotification().Target},
+		OnSuppressed: func(s SuppressedSend) { suppressed = append(suppressed, s) },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := testNotifications(2)
+	ns[1].Target.ID = "0b9a1d4c-5c4a-4a5e-9f3b-7c0a2d6e8f11"
+	results, err := c.NotifyBatch(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Suppressed || !results[1].Suppressed {
+		t.Errorf("expected only the second notification to be suppressed, got %+v", results)
+	}
+	if len(suppressed) != 1 || suppressed[0].Redirected {
+		t.Errorf("expected the drop to be reported, got %+v", suppressed)
+	}
+
+	got := ts.Notifications()
+	if len(got) != 1 || got[0].Title != "[review] "+ns[0].Title {
+		t.Errorf("expected a single notification prefixed once, got %+v", got)
+	}
+
+	res, err := c.Notify(ns[1])
+	if err != nil || !res.Suppressed {
+		t.Errorf("expected Notify to report the drop, got %+v, %v", res, err)
+	}
+}
+
+func TestEnvironmentFollowups(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(miniteltest.GenerateHTTPResponse(t, "staging-id", http.StatusCreated))
+	ts.ExpectFollowup(nil)
+
+	var suppressed []SuppressedSend
+	c, err := New(ts.URL, WithEnvironment(Environment{
+		Name:         "staging",
+		Allow:        []Target{testNotification().Target},
+		OnSuppressed: func(s SuppressedSend) { suppressed = append(suppressed, s) },
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.Notify(testNotification())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Followup(res.ID, "Still down"); err != nil {
+		t.Fatal(err)
+	}
+	// A customer's message from elsewhere, e.g. a production thread.
+	res, err = c.Followup("customer-id", "Fixed")
+	if err != nil || !res.Suppressed {
+		t.Errorf("expected the followup to be dropped, got %+v, %v", res, err)
+	}
+
+	if finished := ts.ExpectDone(time.Second); !finished {
+		t.Error("expected no pending expectations, but some still exist")
+	}
+	got := ts.Followups()
+	if len(got) != 1 || got[0].ID != "staging-id" || got[0].Body != "[staging] Still down" {
+		t.Errorf("expected a single prefixed followup, got %+v", got)
+	}
+	if len(suppressed) != 1 || suppressed[0].FollowupTo != "customer-id" || suppressed[0].Notification.Body != "Fixed" {
+		t.Errorf("expected the dropped followup to be reported, got %+v", suppressed)
+	}
+}
+
+func TestEnvironmentValidation(t *testing.T) {
+	tests := []struct {
+		e   Environment
+		err bool
+	}{
+		{Environment{Name: "staging"}, false},
+		{Environment{Name: "staging", Sandbox: Target{Type: User, ID: "bc31ed62-0204-40e5-86cf-b25a001b20db"}}, false},
+		{Environment{}, true},
+		{Environment{Name: "staging", Sandbox: Target{Type: User, ID: "me@example.com"}}, true},
+		{Environment{Name: "staging", Sandbox: Target{ID: "bc31ed62-0204-40e5-86cf-b25a001b20db"}}, true},
+	}
+	for _, test := range tests {
+		if _, err := New("http://localhost", WithEnvironment(test.e)); (err != nil) != test.err {
+			t.Errorf("WithEnvironment(%+v) expected error %t, got %v", test.e, test.err, err)
+		}
+	}
+}
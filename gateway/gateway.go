@@ -0,0 +1,373 @@
+// Package gateway exposes a minitel.Notifier over a local HTTP API, for tools
+// that can't use this library directly.
+//
+// Callers authenticate with a bearer token and send requests of the form:
+//
+//	POST /notifications                  a minitel.Notification
+//	POST /notifications/{id}/followups   {"body": "..."}
+//
+// Both respond with a 201 and the minitel.Result from Telex. Requests are
+// queued and relayed by a fixed number of workers, retrying failures that
+// minitel.IsRetryable. GET /health and GET /metrics are not authenticated.
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	minitel "github.com/heroku/minitel-go"
+)
+
+// Request outcomes counted by the metrics endpoint.
+const (
+	outcomeSent     = "sent"
+	outcomeInvalid  = "invalid"
+	outcomeRejected = "rejected"
+	outcomeFailed   = "failed"
+)
+
+var (
+	errQueueFull = errors.New("gateway: Queue is full")
+	errClosed    = errors.New("gateway: Closed")
+	errNoBody    = errors.New("gateway: Followup has no body")
+	errInvalidID = errors.New("gateway: Followup message ID is not a UUID")
+)
+
+// Config of a Gateway.
+type Config struct {
+	// Callers maps the name of each caller to the token it authenticates
+	// with.
+	Callers map[string]string
+
+	// Workers relaying requests to Telex. Defaults to 4.
+	Workers int
+
+	// QueueSize is the number of requests waiting for a worker before new
+	// requests are rejected with a 503. Defaults to 100.
+	QueueSize int
+
+	// MaxAttempts at relaying a request. Defaults to 3.
+	MaxAttempts int
+
+	// Backoff before the first retry, doubled for each one after it. Defaults
+	// to 500ms.
+	Backoff time.Duration
+
+	// MaxBodySize is the largest request body accepted, in bytes. Defaults to
+	// 1MB.
+	MaxBodySize int64
+}
+
+// job is a request waiting for a worker.
+type job struct {
+	send func() (minitel.Result, error)
+	done chan jobResult
+}
+
+type jobResult struct {
+	res minitel.Result
+	err error
+}
+
+// Gateway is a http.Handler relaying notifications to a minitel.Notifier.
+type Gateway struct {
+	n           minitel.Notifier
+	callers     map[string]string // token to caller name
+	maxAttempts int
+	backoff     time.Duration
+	maxBodySize int64
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan job
+	wg     sync.WaitGroup
+
+	metrics metrics
+}
+
+// New Gateway relaying through n. Its workers are started immediately and
+// stopped by Close.
+func New(n minitel.Notifier, cfg Config) *Gateway {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 100
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = 1 << 20
+	}
+
+	g := &Gateway{
+		n:           n,
+		callers:     make(map[string]string, len(cfg.Callers)),
+		maxAttempts: cfg.MaxAttempts,
+		backoff:     cfg.Backoff,
+		maxBodySize: cfg.MaxBodySize,
+		queue:       make(chan job, cfg.QueueSize),
+		metrics:     metrics{requests: make(map[[2]string]int)},
+	}
+	for name, token := range cfg.Callers {
+		g.callers[token] = name
+	}
+	g.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go g.work()
+	}
+	return g
+}
+
+// Close stops accepting requests and waits for the queued ones to be relayed.
+func (g *Gateway) Close() {
+	g.mu.Lock()
+	if !g.closed {
+		g.closed = true
+		close(g.queue)
+	}
+	g.mu.Unlock()
+	g.wg.Wait()
+}
+
+// ServeHTTP routes requests to the gateway's endpoints.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/health":
+		g.health(w, r)
+		return
+	case "/metrics":
+		g.serveMetrics(w, r)
+		return
+	}
+
+	caller, ok := g.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="minitel-gateway"`)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, g.maxBodySize)
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "notifications":
+		g.notify(w, r, caller)
+	case len(parts) == 3 && parts[0] == "notifications" && parts[1] != "" && parts[2] == "followups":
+		g.followup(w, r, caller, parts[1])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// authenticate returns the name of the caller whose token r carries.
+func (g *Gateway) authenticate(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return "", false
+	}
+	token := []byte(strings.TrimPrefix(h, "Bearer "))
+
+	var caller string
+	var ok bool
+	for t, name := range g.callers {
+		if subtle.ConstantTimeCompare([]byte(t), token) == 1 {
+			caller, ok = name, true
+		}
+	}
+	return caller, ok
+}
+
+func (g *Gateway) notify(w http.ResponseWriter, r *http.Request, caller string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Unexpected Method: "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	var n minitel.Notification
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&n); err != nil {
+		g.metrics.count(caller, outcomeInvalid)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := n.Validate(); err != nil {
+		g.metrics.count(caller, outcomeInvalid)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	g.relay(w, r, caller, func() (minitel.Result, error) {
+		return g.n.Notify(n)
+	})
+}
+
+func (g *Gateway) followup(w http.ResponseWriter, r *http.Request, caller, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Unexpected Method: "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+	// Checked as it becomes part of the Telex URL.
+	if _, err := uuid.Parse(id); err != nil {
+		g.metrics.count(caller, outcomeInvalid)
+		http.Error(w, errInvalidID.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	var f struct {
+		Body string `json:"body"`
+	}
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&f); err != nil {
+		g.metrics.count(caller, outcomeInvalid)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.Body == "" {
+		g.metrics.count(caller, outcomeInvalid)
+		http.Error(w, errNoBody.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	g.relay(w, r, caller, func() (minitel.Result, error) {
+		return g.n.Followup(id, f.Body)
+	})
+}
+
+// relay queues send and responds with its Result once a worker has run it.
+func (g *Gateway) relay(w http.ResponseWriter, r *http.Request, caller string, send func() (minitel.Result, error)) {
+	j := job{send: send, done: make(chan jobResult, 1)}
+	if err := g.enqueue(j); err != nil {
+		g.metrics.count(caller, outcomeRejected)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	var jr jobResult
+	select {
+	case jr = <-j.done:
+	case <-r.Context().Done():
+		// The caller went away, the worker still relays the request.
+		return
+	}
+	if jr.err != nil {
+		g.metrics.count(caller, outcomeFailed)
+		http.Error(w, jr.err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	g.metrics.count(caller, outcomeSent)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	enc := json.NewEncoder(w)
+	enc.Encode(jr.res)
+}
+
+func (g *Gateway) enqueue(j job) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.closed {
+		return errClosed
+	}
+	select {
+	case g.queue <- j:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+func (g *Gateway) work() {
+	defer g.wg.Done()
+	for j := range g.queue {
+		res, err := g.attempt(j.send)
+		j.done <- jobResult{res: res, err: err}
+	}
+}
+
+// attempt send up to maxAttempts times, backing off between retryable
+// failures.
+func (g *Gateway) attempt(send func() (minitel.Result, error)) (minitel.Result, error) {
+	backoff := g.backoff
+	for i := 1; ; i++ {
+		res, err := send()
+		if err == nil || i >= g.maxAttempts || !minitel.IsRetryable(err) {
+			return res, err
+		}
+		g.metrics.retry()
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (g *Gateway) health(w http.ResponseWriter, r *http.Request) {
+	g.mu.RLock()
+	closed := g.closed
+	g.mu.RUnlock()
+	if closed {
+		http.Error(w, errClosed.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+func (g *Gateway) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	g.metrics.write(w, len(g.queue))
+}
+
+// metrics counted by a Gateway, written in the Prometheus text format.
+type metrics struct {
+	mu       sync.Mutex
+	requests map[[2]string]int // by caller and outcome
+	retries  int
+}
+
+func (m *metrics) count(caller, outcome string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[[2]string{caller, outcome}]++
+}
+
+func (m *metrics) retry() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries++
+}
+
+func (m *metrics) write(w http.ResponseWriter, queueDepth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([][2]string, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	fmt.Fprintln(w, "# TYPE minitel_gateway_requests_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(w, "minitel_gateway_requests_total{caller=%q,outcome=%q} %d\n", k[0], k[1], m.requests[k])
+	}
+	fmt.Fprintln(w, "# TYPE minitel_gateway_retries_total counter")
+	fmt.Fprintf(w, "minitel_gateway_retries_total %d\n", m.retries)
+	fmt.Fprintln(w, "# TYPE minitel_gateway_queue_depth gauge")
+	fmt.Fprintf(w, "minitel_gateway_queue_depth %d\n", queueDepth)
+}
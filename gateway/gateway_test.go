@@ -0,0 +1,126 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	minitel "github.com/heroku/minitel-go"
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+const (
+	token        = "s3cr3t"
+	notification = `{"title": "Deployed", "body": "v42 is live", "target": {"id": "93f90f07-bbe3-433d-806d-2d01bc5ae1f2", "type": "app"}}`
+	threadID     = "727d27f8-589f-45b1-914e-dd613feaf4dc"
+)
+
+func newGateway(t *testing.T, ts *miniteltest.TestServer) *Gateway {
+	c, err := minitel.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(c, Config{
+		Callers: map[string]string{"deployer": token},
+		Backoff: time.Millisecond,
+	})
+}
+
+func do(g *Gateway, method, path, auth, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if auth != "" {
+		r.Header.Set("Authorization", "Bearer "+auth)
+	}
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, r)
+	return w
+}
+
+func TestGateway(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(
+		miniteltest.GenerateHTTPResponse(t, "", http.StatusServiceUnavailable),
+		miniteltest.GenerateHTTPResponse(t, threadID, http.StatusCreated),
+	)
+	ts.ExpectFollowup(miniteltest.GenerateHTTPResponse(t, "followup-id", http.StatusCreated))
+
+	g := newGateway(t, ts)
+	defer g.Close()
+
+	w := do(g, http.MethodPost, "/notifications", token, notification)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var res minitel.Result
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != threadID {
+		t.Errorf("expected the Telex result after a retry, got %+v", res)
+	}
+
+	w = do(g, http.MethodPost, "/notifications/"+threadID+"/followups", token, `{"body": "Rolled back"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	if finished := ts.ExpectDone(time.Second); !finished {
+		t.Error("expected no pending expectations, but some still exist")
+	}
+
+	w = do(g, http.MethodGet, "/metrics", "", "")
+	for _, line := range []string{
+		`minitel_gateway_requests_total{caller="deployer",outcome="sent"} 2`,
+		`minitel_gateway_retries_total 1`,
+		`minitel_gateway_queue_depth 0`,
+	} {
+		if !strings.Contains(w.Body.String(), line+"\n") {
+			t.Errorf("expected metrics to contain %q, got:\n%s", line, w.Body)
+		}
+	}
+}
+
+func TestGatewayErrors(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(miniteltest.GenerateHTTPResponse(t, "", http.StatusUnprocessableEntity))
+
+	g := newGateway(t, ts)
+
+	tests := []struct {
+		name, method, path, auth, body string
+		want                           int
+	}{
+		{"no token", http.MethodPost, "/notifications", "", notification, http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "/notifications", "guess", notification, http.StatusUnauthorized},
+		{"unknown path", http.MethodPost, "/messages", token, notification, http.StatusNotFound},
+		{"wrong method", http.MethodGet, "/notifications", token, "", http.StatusMethodNotAllowed},
+		{"invalid json", http.MethodPost, "/notifications", token, "{", http.StatusBadRequest},
+		{"invalid notification", http.MethodPost, "/notifications", token, `{"title": "No target"}`, http.StatusUnprocessableEntity},
+		{"empty followup", http.MethodPost, "/notifications/" + threadID + "/followups", token, `{}`, http.StatusUnprocessableEntity},
+		{"followup id not a UUID", http.MethodPost, "/notifications/%3F/followups", token, `{"body": "Rolled back"}`, http.StatusUnprocessableEntity},
+		{"body too large", http.MethodPost, "/notifications/" + threadID + "/followups", token, `{"body": "` + strings.Repeat("x", 1<<20) + `"}`, http.StatusBadRequest},
+		// Not retryable, so the single expectation is used once.
+		{"telex error", http.MethodPost, "/notifications", token, notification, http.StatusBadGateway},
+	}
+	for _, test := range tests {
+		if w := do(g, test.method, test.path, test.auth, test.body); w.Code != test.want {
+			t.Errorf("%s: expected %d, got %d", test.name, test.want, w.Code)
+		}
+	}
+
+	if w := do(g, http.MethodGet, "/health", "", ""); w.Code != http.StatusOK {
+		t.Errorf("expected healthy gateway, got %d", w.Code)
+	}
+	g.Close()
+	if w := do(g, http.MethodGet, "/health", "", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected closed gateway to be unhealthy, got %d", w.Code)
+	}
+	if w := do(g, http.MethodPost, "/notifications", token, notification); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected closed gateway to reject requests, got %d", w.Code)
+	}
+}
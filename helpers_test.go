@@ -0,0 +1,19 @@
+package minitel
+
+// testNotification returns a valid Notification to an app.
+func testNotification() Notification {
+	return Notification{
+		Title:  "Hello",
+		Body:   "DB on fire!",
+		Target: Target{Type: App, ID: "93f90f07-bbe3-433d-806d-2d01bc5ae1f2"},
+	}
+}
+
+// testNotifications returns n copies of testNotification.
+func testNotifications(n int) []Notification {
+	ns := make([]Notification, n)
+	for i := range ns {
+		ns[i] = testNotification()
+	}
+	return ns
+}
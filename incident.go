@@ -0,0 +1,202 @@
+package minitel
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// ErrUnknownIncident is returned when resolving an incident that has no
+// thread.
+var ErrUnknownIncident = errors.New("minitel: Unknown incident key")
+
+// defaultResolvedText is the closing Followup of a resolved incident when no
+// text is given.
+const defaultResolvedText = "Resolved."
+
+// IncidentStore persists the ID of the Telex message each incident's
+// Followups are added to, keyed by incident key.
+type IncidentStore interface {
+	Get(key string) (id string, ok bool, err error)
+	Put(key, id string) error
+	Delete(key string) error
+}
+
+// IncidentManager maps incidents onto Telex threads: the first event of an
+// incident creates a Notification and later events are added to it as
+// Followups, until it is resolved. Events are handled one at a time.
+type IncidentManager struct {
+	n     Notifier
+	store IncidentStore
+	mu    sync.Mutex
+}
+
+// NewIncidentManager returns an IncidentManager sending through n and
+// remembering threads in store.
+func NewIncidentManager(n Notifier, store IncidentStore) *IncidentManager {
+	return &IncidentManager{n: n, store: store}
+}
+
+// Event records an event for the incident identified by key. If the incident
+// has no thread yet n is sent to create one, otherwise n.Body is added to the
+// thread as a Followup. Sends that create no message, such as dry runs or
+// suppressed sends, start no thread, so the next Event sends n again.
+func (m *IncidentManager) Event(key string, n Notification) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, ok, err := m.store.Get(key)
+	if err != nil {
+		return Result{}, err
+	}
+	if ok {
+		return m.n.Followup(id, n.Body)
+	}
+
+	res, err := m.n.Notify(n)
+	if err != nil || res.ID == "" {
+		return res, err
+	}
+	return res, m.store.Put(key, res.ID)
+}
+
+// Resolve the incident identified by key, adding text to its thread as a
+// closing Followup. A later Event for key starts a new thread.
+func (m *IncidentManager) Resolve(key, text string) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id, ok, err := m.store.Get(key)
+	if err != nil {
+		return Result{}, err
+	}
+	if !ok {
+		return Result{}, ErrUnknownIncident
+	}
+	if text == "" {
+		text = defaultResolvedText
+	}
+
+	res, err := m.n.Followup(id, text)
+	if err != nil {
+		return res, err
+	}
+	return res, m.store.Delete(key)
+}
+
+// MemoryIncidentStore is an IncidentStore that keeps threads in memory, for
+// when they don't need to survive restarts.
+type MemoryIncidentStore struct {
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+// NewMemoryIncidentStore returns an empty MemoryIncidentStore.
+func NewMemoryIncidentStore() *MemoryIncidentStore {
+	return &MemoryIncidentStore{ids: make(map[string]string)}
+}
+
+// Get the message ID for key.
+func (m *MemoryIncidentStore) Get(key string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, ok := m.ids[key]
+	return id, ok, nil
+}
+
+// Put the message ID for key.
+func (m *MemoryIncidentStore) Put(key, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ids[key] = id
+	return nil
+}
+
+// Delete key.
+func (m *MemoryIncidentStore) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.ids, key)
+	return nil
+}
+
+// FileIncidentStore is an IncidentStore that keeps threads in a JSON file, so
+// that restarts don't create duplicate threads. The file is replaced
+// atomically on every change.
+type FileIncidentStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileIncidentStore returns a FileIncidentStore using the file at path,
+// which is created when first needed.
+func NewFileIncidentStore(path string) *FileIncidentStore {
+	return &FileIncidentStore{path: path}
+}
+
+// Get the message ID for key.
+func (f *FileIncidentStore) Get(key string) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids, err := f.read()
+	if err != nil {
+		return "", false, err
+	}
+	id, ok := ids[key]
+	return id, ok, nil
+}
+
+// Put the message ID for key.
+func (f *FileIncidentStore) Put(key, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids, err := f.read()
+	if err != nil {
+		return err
+	}
+	ids[key] = id
+	return f.write(ids)
+}
+
+// Delete key.
+func (f *FileIncidentStore) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ids, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := ids[key]; !ok {
+		return nil
+	}
+	delete(ids, key)
+	return f.write(ids)
+}
+
+func (f *FileIncidentStore) read() (map[string]string, error) {
+	ids := make(map[string]string)
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return ids, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &ids); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (f *FileIncidentStore) write(ids map[string]string) error {
+	b, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path, b)
+}
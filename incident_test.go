@@ -0,0 +1,113 @@
+package minitel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestIncidentManager(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(
+		miniteltest.GenerateHTTPResponse(t, "thread-1", http.StatusCreated),
+		miniteltest.GenerateHTTPResponse(t, "thread-2", http.StatusCreated),
+	)
+	ts.ExpectFollowup(nil, nil)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "minitel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "incidents.json")
+
+	n := testNotification()
+	m := NewIncidentManager(c, NewFileIncidentStore(path))
+	res, err := m.Event("db-down", n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "thread-1" {
+		t.Errorf("expected first event to create thread-1, got %q", res.ID)
+	}
+
+	// A restarted manager continues the same thread.
+	m = NewIncidentManager(c, NewFileIncidentStore(path))
+	n.Body = "Still down"
+	if _, err := m.Event("db-down", n); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Resolve("db-down", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Resolve("db-down", ""); err != ErrUnknownIncident {
+		t.Errorf("expected ErrUnknownIncident resolving twice, got %v", err)
+	}
+
+	res, err = m.Event("db-down", n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "thread-2" {
+		t.Errorf("expected a new thread after resolving, got %q", res.ID)
+	}
+
+	if finished := ts.ExpectDone(time.Second); !finished {
+		t.Error("expected no pending expectations, but some still exist")
+	}
+	if got := len(ts.Notifications()); got != 2 {
+		t.Errorf("expected 2 threads created, got %d", got)
+	}
+}
+
+func TestIncidentManagerFollowups(t *testing.T) {
+	f := &fakeNotifier{}
+	m := NewIncidentManager(f, NewMemoryIncidentStore())
+
+	n := testNotification()
+	for _, body := range []string{"opened", "update"} {
+		n.Body = body
+		if _, err := m.Event("key", n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Resolve("key", "fixed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(f.sent) != 1 || f.sent[0].Body != "opened" {
+		t.Errorf("expected a single notification, got %+v", f.sent)
+	}
+	if len(f.followups) != 2 || f.followups[0] != "update" || f.followups[1] != "fixed" {
+		t.Errorf("unexpected followups: %q", f.followups)
+	}
+}
+
+func TestIncidentManagerUnthreaded(t *testing.T) {
+	store := NewMemoryIncidentStore()
+	m := NewIncidentManager(NopNotifier{}, store)
+
+	n := testNotification()
+	for i := 0; i < 2; i++ {
+		if _, err := m.Event("key", n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok, _ := store.Get("key"); ok {
+		t.Error("expected a send without a message ID not to start a thread")
+	}
+	if _, err := m.Resolve("key", ""); err != ErrUnknownIncident {
+		t.Errorf("expected ErrUnknownIncident, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package minitel
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var errNoFallbackLocale = errors.New("minitel: FallbackLocale must name one of the Localizations")
+
+// languageTag loosely matches well formed BCP 47 language tags.
+var languageTag = regexp.MustCompile(`^[A-Za-z]{2,8}(-[A-Za-z0-9]{1,8})*$`)
+
+// Localization of a Notification's Title and Body.
+type Localization struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// LocaleResolver looks up the preferred locale of a Target, as a BCP 47
+// language tag.
+type LocaleResolver interface {
+	Locale(t Target) (string, error)
+}
+
+// LocaleResolverFunc adapts a func into a LocaleResolver.
+type LocaleResolverFunc func(t Target) (string, error)
+
+// Locale calls f(t).
+func (f LocaleResolverFunc) Locale(t Target) (string, error) {
+	return f(t)
+}
+
+// WithLocaleResolver sets the LocaleResolver used to pick which of a
+// Notification's Localizations to send. Without one, or when it fails, the
+// FallbackLocale is used.
+func WithLocaleResolver(r LocaleResolver) Option {
+	return func(c *Client) error {
+		c.locales = r
+		return nil
+	}
+}
+
+// WithLocaleErrorHandler sets a func called with the errors of the
+// LocaleResolver, as they don't fail the send.
+func WithLocaleErrorHandler(f func(error)) Option {
+	return func(c *Client) error {
+		c.localeErrors = f
+		return nil
+	}
+}
+
+// validateLocalizations checks the language tags of n's Localizations and
+// that its FallbackLocale is one of them.
+func (n Notification) validateLocalizations() error {
+	if len(n.Localizations) == 0 {
+		return nil
+	}
+	for tag := range n.Localizations {
+		if !languageTag.MatchString(tag) {
+			return fmt.Errorf("minitel: Localization tag is not a BCP 47 language tag: %q", tag)
+		}
+	}
+	if _, ok := n.Localizations[n.FallbackLocale]; !ok {
+		return errNoFallbackLocale
+	}
+	return nil
+}
+
+// Localize returns a copy of n with the Title and Body of the Localization that
+// best matches tag, and without Localizations. Tags are matched ignoring case,
+// first exactly, then by dropping subtags from the end of tag, then by primary
+// language alone. When nothing matches the FallbackLocale is used. n is
+// returned unchanged if it has no Localizations.
+func (n Notification) Localize(tag string) Notification {
+	if len(n.Localizations) == 0 {
+		return n
+	}
+
+	l, ok := matchLocale(n.Localizations, tag)
+	if !ok {
+		l = n.Localizations[n.FallbackLocale]
+	}
+	n.Title, n.Body = l.Title, l.Body
+	n.Localizations = nil
+	n.FallbackLocale = ""
+	return n
+}
+
+func matchLocale(ls map[string]Localization, tag string) (Localization, bool) {
+	if tag == "" {
+		return Localization{}, false
+	}
+	byLower := make(map[string]Localization, len(ls))
+	for t, l := range ls {
+		byLower[strings.ToLower(t)] = l
+	}
+
+	want := strings.ToLower(tag)
+	for {
+		if l, ok := byLower[want]; ok {
+			return l, true
+		}
+		i := strings.LastIndex(want, "-")
+		if i < 0 {
+			break
+		}
+		want = want[:i]
+	}
+
+	// want is now the primary language; look for any regional variant of it.
+	var best string
+	for t := range byLower {
+		if strings.HasPrefix(t, want+"-") && (best == "" || t < best) {
+			best = t
+		}
+	}
+	if best != "" {
+		return byLower[best], true
+	}
+	return Localization{}, false
+}
+
+// localize picks the Localization of n for its Target's locale, or its
+// FallbackLocale if the locale can't be resolved.
+func (c *Client) localize(n Notification) Notification {
+	if len(n.Localizations) == 0 {
+		return n
+	}
+	var tag string
+	if c.locales != nil {
+		var err error
+		if tag, err = c.locales.Locale(n.Target); err != nil {
+			c.localeErrors(err)
+			tag = ""
+		}
+	}
+	return n.Localize(tag)
+}
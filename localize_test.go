@@ -0,0 +1,109 @@
+package minitel
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func localizedNotification() Notification {
+	n := testNotification()
+	n.Localizations = map[string]Localization{
+		"en":    {Title: "Hello", Body: "DB on fire!"},
+		"fr":    {Title: "Bonjour", Body: "La base est en feu !"},
+		"pt-BR": {Title: "Olá", Body: "O banco está pegando fogo!"},
+	}
+	n.FallbackLocale = "en"
+	return n
+}
+
+func TestLocalize(t *testing.T) {
+	n := localizedNotification()
+	for _, tc := range []struct {
+		tag, want string
+	}{
+		{"fr", "Bonjour"},
+		{"FR-ca", "Bonjour"},
+		{"pt-BR", "Olá"},
+		{"pt", "Olá"},
+		{"de", "Hello"},
+		{"", "Hello"},
+	} {
+		got := n.Localize(tc.tag)
+		if got.Title != tc.want {
+			t.Errorf("Localize(%q) title = %q, want %q", tc.tag, got.Title, tc.want)
+		}
+		if got.Localizations != nil || got.FallbackLocale != "" {
+			t.Errorf("Localize(%q) expected localizations to be cleared, got %+v", tc.tag, got)
+		}
+	}
+}
+
+func TestValidateLocalizations(t *testing.T) {
+	n := localizedNotification()
+	if err := n.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	n.FallbackLocale = "de"
+	if err := n.Validate(); err != errNoFallbackLocale {
+		t.Errorf("expected errNoFallbackLocale, got %v", err)
+	}
+
+	n = localizedNotification()
+	n.Localizations["not a tag"] = Localization{}
+	if err := n.Validate(); err == nil {
+		t.Error("expected error for malformed tag but was nil")
+	}
+}
+
+func TestLocaleResolver(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil, nil, nil)
+
+	resolver := LocaleResolverFunc(func(t Target) (string, error) {
+		if t.ID == "93f90f07-bbe3-433d-806d-2d01bc5ae1f2" {
+			return "fr-FR", nil
+		}
+		return "", errors.New("unknown target")
+	})
+	var resolveErrs []error
+	c, err := New(ts.URL, WithLocaleResolver(resolver), WithLocaleErrorHandler(func(err error) {
+		resolveErrs = append(resolveErrs, err)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Notify(localizedNotification()); err != nil {
+		t.Fatal(err)
+	}
+	results, err := c.NotifyBatch([]Notification{localizedNotification()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].ID == "" {
+		t.Error("expected batch result to have an ID")
+	}
+
+	for _, got := range ts.Notifications() {
+		if got.Title != "Bonjour" || got.Body != "La base est en feu !" {
+			t.Errorf("expected french localization to be sent, got %+v", got)
+		}
+	}
+
+	// A resolver error doesn't fail the send, the FallbackLocale is used.
+	other := localizedNotification()
+	other.Target.ID = "bc31ed62-0204-40e5-86cf-b25a001b20db"
+	if _, err := c.Notify(other); err != nil {
+		t.Fatal(err)
+	}
+	if got := ts.Notifications(); len(got) != 3 || got[2].Title != "Hello" {
+		t.Errorf("expected the fallback localization to be sent, got %+v", got)
+	}
+	if len(resolveErrs) != 1 {
+		t.Errorf("expected the resolver error to be reported, got %v", resolveErrs)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -23,6 +24,16 @@ const (
 	Dashboard Type = "dashboard"
 )
 
+// Severity of a Notification.
+type Severity string
+
+// Known Notification severities.
+const (
+	Info     Severity = "info"
+	Warning  Severity = "warning"
+	Critical Severity = "critical"
+)
+
 // Notification message accepted by Telex.
 type Notification struct {
 	Title string `json:"title"`
@@ -30,6 +41,18 @@ type Notification struct {
 
 	Target Target `json:"target"`
 	Action Action `json:"action"`
+
+	// Optional fields that let the receiving side prioritise and group
+	// notifications.
+	Severity Severity          `json:"severity,omitempty"`
+	Category string            `json:"category,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Localizations of Title and Body keyed by BCP 47 language tag. The Client
+	// sends the one matching the Target's locale, or FallbackLocale.
+	Localizations  map[string]Localization `json:"localizations,omitempty"`
+	FallbackLocale string                  `json:"fallback_locale,omitempty"`
 }
 
 // Target portion of a Telex payload. Defined separately to ease construction
@@ -51,6 +74,7 @@ var (
 	errIDNotUUID            = errors.New("minitel: Target.ID not a UUID")
 	errNoTypeSpecified      = errors.New("minitel: Missing Target.Type in Notification")
 	errUnknownTypeSpecified = errors.New("minitel: Target.Type specified ")
+	errUnknownSeverity      = errors.New("minitel: Severity is unknown")
 )
 
 // Validate that a Notification contains everything it needs to.
@@ -69,25 +93,84 @@ func (n Notification) Validate() error {
 	default:
 		return fmt.Errorf("minitel: Specified Target.Type is unknown: %s", n.Target.Type)
 	}
-	return nil
+	switch n.Severity {
+	case "", Info, Warning, Critical:
+	default:
+		return errUnknownSeverity
+	}
+	return n.validateLocalizations()
 }
 
 // Result from telex containing the ID of the created notification.
 type Result struct {
 	ID string `json:"id"`
+
+	// DryRun is set when the Client is in dry run mode and nothing was sent.
+	DryRun bool `json:"-"`
+
+	// Suppressed is set when the Client's Environment dropped the
+	// Notification or Followup and nothing was sent.
+	Suppressed bool `json:"-"`
+}
+
+// Notifier sends Notifications and Followups to Telex. It is implemented by
+// Client and by the types in this package that wrap one.
+type Notifier interface {
+	Notify(n Notification) (Result, error)
+	Followup(id, text string) (Result, error)
+}
+
+// StatusError is returned when Telex responds with an unexpected status code.
+type StatusError struct {
+	Expected, Got int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("minitel: Expected %d: Got %d", e.Expected, e.Got)
+}
+
+// IsRetryable reports whether err is a failure to reach Telex, or a response
+// from Telex, that may not happen if the request is retried.
+func IsRetryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Got >= http.StatusInternalServerError || se.Got == http.StatusTooManyRequests
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
 }
 
 // Client for communicating with telex.
 type Client struct {
 	url, user, pass string
 	*http.Client
+
+	batchSize        int
+	batchUnsupported int32
+
+	gzip          bool
+	gzipThreshold int
+	tls           *tlsSettings
+
+	dryRun func(DryRunRequest)
+
+	locales      LocaleResolver
+	localeErrors func(error)
+	env          *Environment
+
+	decorators []Decorator
+
+	audit func(AuditRecord)
 }
 
+// Option configures a Client. Options are applied in order by New.
+type Option func(*Client) error
+
 // New Telex client targeted at the telex service located at the provided URL.
-func New(URL string) (*Client, error) {
+func New(URL string, opts ...Option) (*Client, error) {
 	u, err := url.Parse(URL)
 	if err != nil {
-		return nil, err
+		return nil, redactError(err, "")
 	}
 
 	var user, pass string
@@ -97,71 +180,130 @@ func New(URL string) (*Client, error) {
 		u.User = nil
 	}
 
-	return &Client{
-		url:    u.String(),
-		user:   user,
-		pass:   pass,
-		Client: http.DefaultClient,
-	}, nil
+	c := &Client{
+		url:          u.String(),
+		user:         user,
+		pass:         pass,
+		Client:       http.DefaultClient,
+		batchSize:    defaultBatchSize,
+		localeErrors: func(error) {},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.configureTransport(); err != nil {
+		return nil, err
+	}
+	return c, nil
 }
 
 // Notify Telex.
-func (c *Client) Notify(n Notification) (result Result, err error) {
-	// Validate the notification before trying to send.
-	if err := n.Validate(); err != nil {
-		return result, err
-	}
+func (c *Client) Notify(n Notification) (Result, error) {
+	return c.notify(n, true)
+}
 
-	var buf bytes.Buffer
-	enc := json.NewEncoder(&buf)
-	if err := enc.Encode(n); err != nil {
-		return result, err
-	}
+// NotifyUndecorated notifies Telex without applying the Client's Decorators,
+// for sends that must go out exactly as given.
+func (c *Client) NotifyUndecorated(n Notification) (Result, error) {
+	return c.notify(n, false)
+}
 
-	req, err := c.postRequest(c.url+"/producer/messages", &buf)
-	if err != nil {
-		return result, err
+func (c *Client) notify(n Notification, decorate bool) (result Result, err error) {
+	if c.audit != nil {
+		defer func(start time.Time) {
+			c.record(AuditRecord{Op: AuditNotify, Target: n.Target, Title: n.Title, Body: n.Body}, start, result, err)
+		}(time.Now())
 	}
 
-	resp, err := c.Client.Do(req)
+	n, err = c.prepare(n, decorate)
+	if err == errSuppressed {
+		return Result{Suppressed: true}, nil
+	}
 	if err != nil {
 		return result, err
 	}
-	defer resp.Body.Close()
+	result, err = c.send(n)
+	if err == nil {
+		c.environmentSent(result)
+	}
+	return result, err
+}
 
-	if resp.StatusCode != http.StatusCreated {
-		return result, fmt.Errorf("minitel: Expected 201: Got %d", resp.StatusCode)
+// prepare n for sending: decorate it if decorate is set, validate it, pick
+// its localization and apply the Client's Environment. errSuppressed is
+// returned if n must not be sent.
+func (c *Client) prepare(n Notification, decorate bool) (Notification, error) {
+	var err error
+	if decorate {
+		if n, err = c.decorate(n); err != nil {
+			return n, err
+		}
 	}
+	// Validate the notification before trying to send.
+	if err := n.Validate(); err != nil {
+		return n, err
+	}
+	return c.applyEnvironment(c.localize(n))
+}
 
-	dec := json.NewDecoder(resp.Body)
-	if err := dec.Decode(&result); err != nil {
-		return result, err
+// send the prepared n.
+func (c *Client) send(n Notification) (Result, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(n); err != nil {
+		return Result{}, err
 	}
-	return result, nil
+
+	return c.post("/producer/messages", &buf)
 }
 
 // Followup adds some additional text to the previously created notification
 // identified by id.
 func (c *Client) Followup(id, text string) (result Result, err error) {
+	if c.audit != nil {
+		defer func(start time.Time) {
+			c.record(AuditRecord{Op: AuditFollowup, MessageID: id, Body: text}, start, result, err)
+		}(time.Now())
+	}
+
+	text, err = c.applyEnvironmentFollowup(id, text)
+	if err == errSuppressed {
+		return Result{Suppressed: true}, nil
+	}
+	if err != nil {
+		return result, err
+	}
+
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 	if err := enc.Encode(map[string]string{"body": text}); err != nil {
 		return result, err
 	}
 
-	req, err := c.postRequest(c.url+"/producer/messages/"+id+"/followups", &buf)
+	return c.post("/producer/messages/"+url.PathEscape(id)+"/followups", &buf)
+}
+
+// post the JSON in buf to path and decode the Result Telex responds with.
+func (c *Client) post(path string, buf io.Reader) (result Result, err error) {
+	req, err := c.NewRequest(http.MethodPost, path, buf)
 	if err != nil {
 		return result, err
 	}
 
+	if c.dryRun != nil {
+		return Result{DryRun: true}, c.dryRunRequest(req)
+	}
+
 	resp, err := c.Client.Do(req)
 	if err != nil {
-		return result, err
+		return result, c.Redact(err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
-		return result, fmt.Errorf("minitel: Expected 201: Got %d", resp.StatusCode)
+		return result, &StatusError{Expected: http.StatusCreated, Got: resp.StatusCode}
 	}
 
 	dec := json.NewDecoder(resp.Body)
@@ -171,8 +313,12 @@ func (c *Client) Followup(id, text string) (result Result, err error) {
 	return result, nil
 }
 
-func (c *Client) postRequest(url string, buf io.Reader) (*http.Request, error) {
-	req, err := http.NewRequest(http.MethodPost, url, buf)
+// NewRequest returns a http.Request for the given method and path, relative to
+// the Telex URL, with the Client's credentials and JSON content type set. It is
+// used by the Client itself and by packages, like consumer, that speak to other
+// parts of the Telex API.
+func (c *Client) NewRequest(method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.url+path, body)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,82 @@
+package miniteltest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// batchItem mirrors the per Notification response to a batch request.
+type batchItem struct {
+	ID     string `json:"id,omitempty"`
+	Status int    `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// DisableBatch makes the batch endpoint respond with http.StatusNotFound, as
+// a Telex without batch support would, so that clients fall back to sending
+// Notifications one at a time.
+func (ts *TestServer) DisableBatch() {
+	ts.Lock()
+	defer ts.Unlock()
+	ts.batchDisabled = true
+}
+
+// BatchRequests returns the number of batch requests the TestServer has
+// accepted.
+func (ts *TestServer) BatchRequests() int {
+	ts.Lock()
+	defer ts.Unlock()
+	return ts.batchRequests
+}
+
+func (ts *TestServer) batchHandler(w http.ResponseWriter, r *http.Request) {
+	if ts.batchDisabled {
+		http.Error(w, "Batch not supported", http.StatusNotFound)
+		return
+	}
+
+	var ns []ReceivedNotification
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&ns); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ts.batchRequests++
+	ts.received = append(ts.received, ns...)
+
+	items := make([]batchItem, len(ns))
+	for i := range ns {
+		if len(ts.notifyResponses) == 0 {
+			items[i] = batchItem{Status: http.StatusInternalServerError, Error: "No Notify Response Expecations"}
+			continue
+		}
+		resp := ts.notifyResponses[0]
+		ts.notifyResponses = ts.notifyResponses[1:]
+		items[i] = toBatchItem(resp)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	enc := json.NewEncoder(w)
+	enc.Encode(items)
+}
+
+// toBatchItem converts an expected Notify response into its batch equivalent.
+func toBatchItem(resp *http.Response) batchItem {
+	if resp == nil {
+		return batchItem{ID: uuid.New().String(), Status: http.StatusCreated}
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return batchItem{Status: resp.StatusCode}
+	}
+
+	var res result
+	if resp.Body != nil {
+		dec := json.NewDecoder(resp.Body)
+		if err := dec.Decode(&res); err != nil {
+			return batchItem{Status: http.StatusInternalServerError, Error: err.Error()}
+		}
+	}
+	return batchItem{ID: res.ID, Status: http.StatusCreated}
+}
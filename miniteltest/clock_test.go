@@ -0,0 +1,39 @@
+package miniteltest
+
+import (
+	"testing"
+	"time"
+
+	minitel "github.com/heroku/minitel-go"
+)
+
+var _ minitel.Clock = (*Clock)(nil)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClock(start)
+
+	var fired []time.Time
+	c.AfterFunc(2*time.Second, func() { fired = append(fired, c.Now()) })
+	c.AfterFunc(time.Second, func() { fired = append(fired, c.Now()) })
+	stop := c.AfterFunc(time.Second, func() { t.Error("stopped timer fired") })
+	if !stop() {
+		t.Error("expected stop to report the timer was stopped")
+	}
+
+	c.Advance(1500 * time.Millisecond)
+	if len(fired) != 1 || !fired[0].Equal(start.Add(time.Second)) {
+		t.Fatalf("expected one timer to fire at +1s, got %v", fired)
+	}
+	if got := c.Now(); !got.Equal(start.Add(1500 * time.Millisecond)) {
+		t.Errorf("expected Now to be +1.5s, got %v", got)
+	}
+
+	c.Advance(time.Second)
+	if len(fired) != 2 || !fired[1].Equal(start.Add(2*time.Second)) {
+		t.Fatalf("expected second timer to fire at +2s, got %v", fired)
+	}
+	if stop() {
+		t.Error("expected stop to report false for an already stopped timer")
+	}
+}
@@ -0,0 +1,184 @@
+package miniteltest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultMax is the page size used by the consumer endpoints when the request
+// doesn't specify one.
+const defaultMax = 20
+
+// UserNotification stored by the TestServer and served by its consumer
+// endpoints. Use AddUserNotification to make one available.
+type UserNotification struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+
+	Action    Action         `json:"action"`
+	Read      bool           `json:"read"`
+	CreatedAt time.Time      `json:"created_at"`
+	Followups []UserFollowup `json:"followup,omitempty"`
+}
+
+// Action of a UserNotification.
+type Action struct {
+	Label string `json:"label"`
+	URL   string `json:"url"`
+}
+
+// UserFollowup of a UserNotification.
+type UserFollowup struct {
+	ID        string    `json:"id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AddUserNotification makes n available through the consumer endpoints and
+// returns its ID. A random ID and the current time are filled in when n
+// doesn't specify them.
+func (ts *TestServer) AddUserNotification(n UserNotification) string {
+	ts.Lock()
+	defer ts.Unlock()
+
+	if n.ID == "" {
+		n.ID = uuid.New().String()
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+	ts.userNotifications = append(ts.userNotifications, n)
+	return n.ID
+}
+
+// UserNotification returns the stored notification identified by id, which
+// is useful to check the effects of marking it read or unread.
+func (ts *TestServer) UserNotification(id string) (UserNotification, bool) {
+	ts.Lock()
+	defer ts.Unlock()
+
+	if i := ts.userNotificationIndex(id); i >= 0 {
+		return ts.userNotifications[i], true
+	}
+	return UserNotification{}, false
+}
+
+func (ts *TestServer) userNotificationIndex(id string) int {
+	for i, n := range ts.userNotifications {
+		if n.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ts *TestServer) consumerHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/user/notifications" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Unexpected Method: "+r.Method, http.StatusInternalServerError)
+			return
+		}
+		ts.listHandler(w, r)
+		return
+	}
+
+	i := ts.userNotificationIndex(strings.TrimPrefix(r.URL.Path, "/user/notifications/"))
+	if i < 0 {
+		http.Error(w, "Unknown notification: "+r.URL.Path, http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+		enc.Encode(ts.userNotifications[i])
+	case http.MethodPatch:
+		var p struct {
+			Read *bool `json:"read"`
+		}
+		dec := json.NewDecoder(r.Body)
+		if err := dec.Decode(&p); err != nil || p.Read == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ts.userNotifications[i].Read = *p.Read
+		w.WriteHeader(http.StatusOK)
+		enc := json.NewEncoder(w)
+		enc.Encode(ts.userNotifications[i])
+	default:
+		http.Error(w, "Unexpected Method: "+r.Method, http.StatusInternalServerError)
+	}
+}
+
+// listHandler serves pages of notifications using Heroku style Range headers
+// of the form "id ]<last seen id>..; max=<n>".
+func (ts *TestServer) listHandler(w http.ResponseWriter, r *http.Request) {
+	after, max, err := parseRange(r.Header.Get("Range"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	start := 0
+	if after != "" {
+		start = ts.userNotificationIndex(after) + 1
+		if start == 0 {
+			http.Error(w, "Unknown range start: "+after, http.StatusBadRequest)
+			return
+		}
+	}
+	end := start + max
+	if end > len(ts.userNotifications) {
+		end = len(ts.userNotifications)
+	}
+
+	// Followups are only returned when fetching a single notification.
+	page := make([]UserNotification, 0, end-start)
+	for _, n := range ts.userNotifications[start:end] {
+		n.Followups = nil
+		page = append(page, n)
+	}
+
+	status := http.StatusOK
+	if end < len(ts.userNotifications) {
+		status = http.StatusPartialContent
+		w.Header().Set("Next-Range", fmt.Sprintf("id ]%s..; max=%d", page[len(page)-1].ID, max))
+	}
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.Encode(page)
+}
+
+func parseRange(rng string) (after string, max int, err error) {
+	max = defaultMax
+	if rng == "" {
+		return after, max, nil
+	}
+
+	parts := strings.Split(rng, ";")
+	bounds := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(parts[0]), "id"))
+	if !strings.HasSuffix(bounds, "..") {
+		return after, max, fmt.Errorf("Unexpected Range: %q", rng)
+	}
+	after = strings.TrimPrefix(strings.TrimSuffix(bounds, ".."), "]")
+
+	for _, p := range parts[1:] {
+		p = strings.TrimSpace(p)
+		if !strings.HasPrefix(p, "max=") {
+			continue
+		}
+		max, err = strconv.Atoi(strings.TrimPrefix(p, "max="))
+		if err != nil || max <= 0 {
+			return after, max, fmt.Errorf("Unexpected Range: %q", rng)
+		}
+	}
+	return after, max, nil
+}
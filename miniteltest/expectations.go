@@ -20,13 +20,24 @@ import (
 // http.Responses with the handler for controlled testing of responses. Or call
 // those methods with nil to get a generic response. The ExpectDone() method can be
 // used to ensure that all expectations have happened within the provided
-// timeout, which is useful for when the client is used async.
+// timeout, which is useful for when the client is used async. Batch requests
+// use up one ExpectNotify response per Notification. Gzip encoded requests are
+// decoded transparently. The consumer endpoints serve the notifications added
+// with AddUserNotification.
 type TestServer struct {
 	*httptest.Server
 
 	sync.Mutex
 	notifyResponses   []*http.Response
 	followupResponses []*http.Response
+	userNotifications []UserNotification
+	received          []ReceivedNotification
+	receivedFollowups []ReceivedFollowup
+	batchDisabled     bool
+	batchRequests     int
+
+	compressResponses  bool
+	compressedRequests int
 }
 
 // Here so we don't have to import minitel
@@ -34,6 +45,29 @@ type result struct {
 	ID string `json:"id"`
 }
 
+// ReceivedNotification is a Notification as received by the TestServer.
+type ReceivedNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+
+	Target struct {
+		Type string `json:"type"`
+		ID   string `json:"id"`
+	} `json:"target"`
+	Action Action `json:"action"`
+
+	Severity string            `json:"severity"`
+	Category string            `json:"category"`
+	Tags     []string          `json:"tags"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// ReceivedFollowup is a Followup as received by the TestServer.
+type ReceivedFollowup struct {
+	ID   string
+	Body string
+}
+
 // NewServer returns a prepared TestServer which should be used like a httptest.Server
 //
 //    ts := NewServer()
@@ -41,32 +75,46 @@ type result struct {
 //
 func NewServer() *TestServer {
 	var ts TestServer
-	ts.Server = httptest.NewServer(
-		http.HandlerFunc(
-			func(w http.ResponseWriter, r *http.Request) {
-				ts.Lock()
-				defer ts.Unlock()
-				if r.Method != http.MethodPost {
-					http.Error(w, "Unexpected Method: "+r.Method, http.StatusInternalServerError)
-					return
-				}
-				if r.URL.Path == "/producer/messages" {
-					ts.notifyHandler(w, r)
-					return
-				}
-
-				if strings.HasPrefix(r.URL.Path, "/producer/messages/") && strings.HasSuffix(r.URL.Path, "/followups") {
-					ts.followupHandler(w, r)
-					return
-				}
-
-				http.Error(w, "Unexpected path: "+r.URL.Path, http.StatusInternalServerError)
-			},
-		),
-	)
+	ts.Server = httptest.NewServer(http.HandlerFunc(ts.handle))
 	return &ts
 }
 
+func (ts *TestServer) handle(w http.ResponseWriter, r *http.Request) {
+	ts.Lock()
+	defer ts.Unlock()
+	if err := ts.decodeRequest(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w, done := ts.encodeResponse(w, r)
+	defer done()
+
+	if strings.HasPrefix(r.URL.Path, "/user/notifications") {
+		ts.consumerHandler(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Unexpected Method: "+r.Method, http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Path == "/producer/messages" {
+		ts.notifyHandler(w, r)
+		return
+	}
+
+	if r.URL.Path == "/producer/messages/batch" {
+		ts.batchHandler(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/producer/messages/") && strings.HasSuffix(r.URL.Path, "/followups") {
+		ts.followupHandler(w, r)
+		return
+	}
+
+	http.Error(w, "Unexpected path: "+r.URL.Path, http.StatusInternalServerError)
+}
+
 func doResponse(resp *http.Response, w http.ResponseWriter) {
 	if resp == nil {
 		w.WriteHeader(http.StatusCreated)
@@ -86,13 +134,14 @@ func doResponse(resp *http.Response, w http.ResponseWriter) {
 }
 
 func (ts *TestServer) notifyHandler(w http.ResponseWriter, r *http.Request) {
-	var n interface{}
+	var n ReceivedNotification
 	dec := json.NewDecoder(r.Body)
 	err := dec.Decode(&n)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	ts.received = append(ts.received, n)
 
 	if len(ts.notifyResponses) == 0 {
 		http.Error(w, "No Notify Response Expecations", http.StatusInternalServerError)
@@ -118,6 +167,8 @@ func (ts *TestServer) followupHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/producer/messages/"), "/followups")
+	ts.receivedFollowups = append(ts.receivedFollowups, ReceivedFollowup{ID: id, Body: p.Body})
 
 	if len(ts.followupResponses) == 0 {
 		http.Error(w, "No Followup Response Expectations", http.StatusInternalServerError)
@@ -156,6 +207,22 @@ func (ts *TestServer) ExpectFollowup(r ...*http.Response) {
 	ts.followupResponses = append(ts.followupResponses, r...)
 }
 
+// Notifications returns every Notification received so far, in the order they
+// were received, so that tests can make assertions about what was sent.
+func (ts *TestServer) Notifications() []ReceivedNotification {
+	ts.Lock()
+	defer ts.Unlock()
+	return append([]ReceivedNotification(nil), ts.received...)
+}
+
+// Followups returns every Followup received so far, in the order they were
+// received.
+func (ts *TestServer) Followups() []ReceivedFollowup {
+	ts.Lock()
+	defer ts.Unlock()
+	return append([]ReceivedFollowup(nil), ts.receivedFollowups...)
+}
+
 // ExpectDone waits up to max duration for all notify and followup responses to
 // be sent. Returns true if they have been sent. If they haven't been sent after
 // the max duration then return false.
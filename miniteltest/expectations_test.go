@@ -151,6 +151,9 @@ func TestExpectFollowup(t *testing.T) {
 	if r.ID == "" {
 		t.Error("expected the ID to not be blank, but it was")
 	}
+	if got := ts.Followups(); len(got) != 1 || got[0].ID != "testid" || got[0].Body != "testtext" {
+		t.Errorf("unexpected followups: %+v", got)
+	}
 }
 
 func TestExpectNoFollowup(t *testing.T) {
@@ -166,3 +169,38 @@ func TestExpectNoFollowup(t *testing.T) {
 		t.Fatal("expected error but was nil")
 	}
 }
+
+func TestNotifications(t *testing.T) {
+	ts := NewServer()
+	defer ts.Close()
+
+	ts.ExpectNotify(nil)
+
+	c, err := minitel.New(ts.URL)
+	if err != nil {
+		t.Fatal("unable to setup test client: ", err)
+	}
+
+	sent := n
+	sent.Severity = minitel.Critical
+	sent.Category = "database"
+	sent.Tags = []string{"postgres"}
+	sent.Metadata = map[string]string{"plan": "standard-0"}
+	if _, err := c.Notify(sent); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	got := ts.Notifications()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(got))
+	}
+	if got[0].Title != sent.Title || got[0].Target.ID != sent.Target.ID {
+		t.Errorf("unexpected notification: %+v", got[0])
+	}
+	if got[0].Severity != "critical" || got[0].Category != "database" {
+		t.Errorf("expected severity and category to be captured, got %+v", got[0])
+	}
+	if len(got[0].Tags) != 1 || got[0].Tags[0] != "postgres" || got[0].Metadata["plan"] != "standard-0" {
+		t.Errorf("expected tags and metadata to be captured, got %+v", got[0])
+	}
+}
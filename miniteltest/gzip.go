@@ -0,0 +1,70 @@
+package miniteltest
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// CompressResponses makes the TestServer gzip its responses to requests that
+// accept gzip encoding.
+func (ts *TestServer) CompressResponses() {
+	ts.Lock()
+	defer ts.Unlock()
+	ts.compressResponses = true
+}
+
+// CompressedRequests returns the number of gzip encoded requests the
+// TestServer has received.
+func (ts *TestServer) CompressedRequests() int {
+	ts.Lock()
+	defer ts.Unlock()
+	return ts.compressedRequests
+}
+
+// decodeRequest transparently decompresses gzip encoded request bodies.
+func (ts *TestServer) decodeRequest(r *http.Request) error {
+	if r.Header.Get("Content-Encoding") != "gzip" {
+		return nil
+	}
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body = ioutil.NopCloser(zr)
+	r.Header.Del("Content-Encoding")
+	ts.compressedRequests++
+	return nil
+}
+
+// encodeResponse wraps w so the response is compressed if configured and
+// accepted. The returned func must be called once the response is written.
+func (ts *TestServer) encodeResponse(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	if !ts.compressResponses || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		return w, func() {}
+	}
+	gw := &gzipResponseWriter{ResponseWriter: w, zw: gzip.NewWriter(w)}
+	return gw, func() { gw.zw.Close() }
+}
+
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	zw          *gzip.Writer
+	wroteHeader bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	g.Header().Del("Content-Length")
+	g.Header().Set("Content-Encoding", "gzip")
+	g.ResponseWriter.WriteHeader(code)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	g.WriteHeader(http.StatusOK)
+	return g.zw.Write(b)
+}
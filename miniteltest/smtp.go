@@ -0,0 +1,125 @@
+package miniteltest
+
+import (
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// SMTPMessage is a message accepted by an SMTPServer.
+type SMTPMessage struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// SMTPServer is an in-process SMTP server that accepts every message sent to
+// it, for testing code that sends email. It speaks just enough SMTP for
+// net/smtp.SendMail without auth or STARTTLS.
+type SMTPServer struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+
+	l  net.Listener
+	wg sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []SMTPMessage
+}
+
+// NewSMTPServer returns a started SMTPServer which should be closed when done
+//
+//	ss := NewSMTPServer()
+//	defer ss.Close()
+func NewSMTPServer() *SMTPServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic("miniteltest: failed to listen: " + err.Error())
+	}
+	ss := &SMTPServer{Addr: l.Addr().String(), l: l}
+	ss.wg.Add(1)
+	go ss.serve()
+	return ss
+}
+
+// Close the server, waiting for open connections to finish.
+func (ss *SMTPServer) Close() {
+	ss.l.Close()
+	ss.wg.Wait()
+}
+
+// Messages returns the messages accepted so far.
+func (ss *SMTPServer) Messages() []SMTPMessage {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	return append([]SMTPMessage(nil), ss.messages...)
+}
+
+func (ss *SMTPServer) serve() {
+	defer ss.wg.Done()
+	for {
+		conn, err := ss.l.Accept()
+		if err != nil {
+			return
+		}
+		ss.wg.Add(1)
+		go func() {
+			defer ss.wg.Done()
+			defer conn.Close()
+			ss.converse(textproto.NewConn(conn))
+		}()
+	}
+}
+
+func (ss *SMTPServer) converse(c *textproto.Conn) {
+	var msg SMTPMessage
+	c.PrintfLine("220 localhost miniteltest")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		arg := strings.TrimSpace(line[len(verb):])
+
+		switch verb {
+		case "EHLO", "HELO":
+			c.PrintfLine("250 localhost")
+		case "MAIL":
+			msg = SMTPMessage{From: address(arg)}
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, address(arg))
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := ioutil.ReadAll(c.DotReader())
+			if err != nil {
+				return
+			}
+			msg.Data = data
+			ss.mu.Lock()
+			ss.messages = append(ss.messages, msg)
+			ss.mu.Unlock()
+			c.PrintfLine("250 OK")
+		case "RSET", "NOOP":
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 Bye")
+			return
+		default:
+			c.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// address extracts the address from a MAIL FROM:<a> or RCPT TO:<a> argument.
+func address(arg string) string {
+	i, j := strings.IndexByte(arg, '<'), strings.IndexByte(arg, '>')
+	if i < 0 || j < i {
+		return ""
+	}
+	return arg[i+1 : j]
+}
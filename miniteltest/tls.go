@@ -0,0 +1,40 @@
+package miniteltest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+)
+
+// NewTLSServer returns a prepared TestServer serving HTTPS with a self signed
+// certificate. Use CertificatePEM to trust it.
+func NewTLSServer() *TestServer {
+	var ts TestServer
+	ts.Server = httptest.NewTLSServer(http.HandlerFunc(ts.handle))
+	return &ts
+}
+
+// NewTLSServerWithClientCAs returns a prepared TestServer serving HTTPS that
+// rejects clients not presenting a certificate signed by one of clientCAs.
+func NewTLSServerWithClientCAs(clientCAs *x509.CertPool) *TestServer {
+	var ts TestServer
+	ts.Server = httptest.NewUnstartedServer(http.HandlerFunc(ts.handle))
+	ts.Server.TLS = &tls.Config{
+		ClientCAs:  clientCAs,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+	}
+	ts.Server.StartTLS()
+	return &ts
+}
+
+// CertificatePEM returns the PEM encoded certificate of a TLS TestServer, or
+// nil if it doesn't serve HTTPS.
+func (ts *TestServer) CertificatePEM() []byte {
+	cert := ts.Certificate()
+	if cert == nil {
+		return nil
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
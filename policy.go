@@ -0,0 +1,322 @@
+package minitel
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	errPolicyNoPeriod   = errors.New("minitel: PolicyRule with a Limit must have a positive Per")
+	errQuietHoursBounds = errors.New("minitel: QuietHours Start and End must be within 0 to 24h")
+)
+
+// PolicyOutcome is what a Policy did with a Notification.
+type PolicyOutcome string
+
+// Outcomes of a PolicyDecision.
+const (
+	PolicySend  PolicyOutcome = "send"
+	PolicyDefer PolicyOutcome = "defer"
+	PolicyDrop  PolicyOutcome = "drop"
+)
+
+// Reasons given in a PolicyDecision that isn't to send.
+const (
+	ReasonQuietHours = "quiet hours"
+	ReasonRateLimit  = "rate limit"
+)
+
+// QuietHours is a daily period during which Notifications aren't sent. Start
+// and End are offsets from midnight in Location, or UTC if nil. If End is
+// before Start the period spans midnight, e.g. 22h to 7h.
+type QuietHours struct {
+	Start, End time.Duration
+	Location   *time.Location
+}
+
+// until returns the end of the quiet period t is in, if it is in one.
+func (q QuietHours) until(t time.Time) (time.Time, bool) {
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	d := t.Sub(midnight)
+
+	switch {
+	case q.Start <= q.End && d >= q.Start && d < q.End:
+		return midnight.Add(q.End), true
+	case q.Start > q.End && d >= q.Start:
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc).Add(q.End), true
+	case q.Start > q.End && d < q.End:
+		return midnight.Add(q.End), true
+	}
+	return time.Time{}, false
+}
+
+// PolicyRule restricts sends to the Targets it matches. A Target with an empty
+// Type or ID matches any. Notifications with a Severity in Exempt are not
+// restricted.
+type PolicyRule struct {
+	Target Target
+	Exempt []Severity
+
+	// QuietHours, if set, defers or drops Notifications sent during them.
+	QuietHours *QuietHours
+
+	// Limit, if set, is the maximum number of Notifications sent to each
+	// matching Target Per period. Later ones are deferred to the next period or
+	// dropped.
+	Limit int
+	Per   time.Duration
+
+	// Drop Notifications rather than deferring them.
+	Drop bool
+}
+
+func (r PolicyRule) validate() error {
+	if r.Limit > 0 && r.Per <= 0 {
+		return errPolicyNoPeriod
+	}
+	if q := r.QuietHours; q != nil {
+		for _, d := range []time.Duration{q.Start, q.End} {
+			if d < 0 || d > 24*time.Hour {
+				return errQuietHoursBounds
+			}
+		}
+	}
+	return nil
+}
+
+func (r PolicyRule) matches(n Notification) bool {
+	if r.Target.Type != "" && r.Target.Type != n.Target.Type {
+		return false
+	}
+	if r.Target.ID != "" && r.Target.ID != n.Target.ID {
+		return false
+	}
+	for _, s := range r.Exempt {
+		if s == n.Severity {
+			return false
+		}
+	}
+	return true
+}
+
+// PolicyDecision reports what a Policy did with a Notification.
+type PolicyDecision struct {
+	Outcome PolicyOutcome
+
+	// Reason the Notification was deferred or dropped.
+	Reason string
+
+	// SendAt and Handle of a deferred Notification, see Scheduler.
+	SendAt time.Time
+	Handle string
+}
+
+// PolicyOption configures a Policy.
+type PolicyOption func(*Policy)
+
+// WithPolicyScheduleStore sets the store deferred Notifications are kept in
+// until the Policy's Scheduler sends them. Without one they are dropped
+// instead.
+func WithPolicyScheduleStore(store ScheduleStore) PolicyOption {
+	return func(p *Policy) {
+		p.store = store
+	}
+}
+
+// WithPolicyClock sets the Clock used for quiet hours and rate limits.
+func WithPolicyClock(c Clock) PolicyOption {
+	return func(p *Policy) {
+		p.clock = c
+	}
+}
+
+// WithPolicyDecisionHandler sets a func called with every decision, for when
+// the Policy is used as a Notifier.
+func WithPolicyDecisionHandler(f func(Notification, PolicyDecision)) PolicyOption {
+	return func(p *Policy) {
+		p.onDecision = f
+	}
+}
+
+// WithPolicyErrorHandler sets a func called with errors sending deferred
+// Notifications, as they are sent asynchronously.
+func WithPolicyErrorHandler(f func(error)) PolicyOption {
+	return func(p *Policy) {
+		p.onError = f
+	}
+}
+
+// policyWindow counts the Notifications sent to a Target in a rate limit
+// period.
+type policyWindow struct {
+	start time.Time
+	sent  int
+}
+
+// Policy sits in front of a Notifier, applying the first PolicyRule matching
+// each Notification. Notifications matching no rule are sent. Followups are
+// always sent. Deferred Notifications are sent back through the Policy when
+// due, so they count against the rate limit of the period they are sent in and
+// may be deferred again.
+type Policy struct {
+	Notifier
+	rules      []PolicyRule
+	store      ScheduleStore
+	scheduler  *Scheduler
+	clock      Clock
+	onDecision func(Notification, PolicyDecision)
+	onError    func(error)
+
+	mu      sync.Mutex
+	windows map[policyKey]*policyWindow
+	pruneAt int
+}
+
+type policyKey struct {
+	rule   int
+	target Target
+}
+
+// minPolicyPrune is the number of rate limit windows kept before expired ones
+// are pruned.
+const minPolicyPrune = 64
+
+// NewPolicy returns a Policy applying rules to Notifications sent through n.
+// Deferred Notifications already in the store are picked up, see
+// NewScheduler.
+func NewPolicy(n Notifier, rules []PolicyRule, opts ...PolicyOption) (*Policy, error) {
+	for _, r := range rules {
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &Policy{
+		Notifier:   n,
+		rules:      rules,
+		clock:      SystemClock,
+		onDecision: func(Notification, PolicyDecision) {},
+		onError:    func(error) {},
+		windows:    make(map[policyKey]*policyWindow),
+		pruneAt:    minPolicyPrune,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.store != nil {
+		// Held so overdue Notifications sent right away wait for the
+		// Scheduler to be set.
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		var err error
+		p.scheduler, err = NewScheduler(p, p.store, WithScheduleClock(p.clock), WithDeliveryHandler(func(_ string, _ Result, err error) {
+			if err != nil {
+				p.onError(err)
+			}
+		}))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// Close stops sending deferred Notifications. Those pending remain in the
+// store.
+func (p *Policy) Close() error {
+	if p.scheduler == nil {
+		return nil
+	}
+	return p.scheduler.Close()
+}
+
+// Notify sends, defers or drops n. The Result is empty unless it was sent.
+func (p *Policy) Notify(n Notification) (Result, error) {
+	_, res, err := p.Send(n)
+	return res, err
+}
+
+// Send sends, defers or drops n, reporting which.
+func (p *Policy) Send(n Notification) (PolicyDecision, Result, error) {
+	d, scheduler := p.decide(n)
+	if d.Outcome == PolicyDefer {
+		if scheduler == nil {
+			d = PolicyDecision{Outcome: PolicyDrop, Reason: d.Reason}
+		} else {
+			var err error
+			if d.Handle, err = scheduler.Schedule(d.SendAt, n); err != nil {
+				return d, Result{}, err
+			}
+		}
+	}
+	p.onDecision(n, d)
+	if d.Outcome != PolicySend {
+		return d, Result{}, nil
+	}
+
+	res, err := p.Notifier.Notify(n)
+	return d, res, err
+}
+
+// decide whether to send n now, counting it against its rate limit if so, or
+// when to defer it to and the Scheduler to defer it with, nil if it must be
+// dropped instead.
+func (p *Policy) decide(n Notification) (PolicyDecision, *Scheduler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, r := range p.rules {
+		if !r.matches(n) {
+			continue
+		}
+		now := p.clock.Now()
+		scheduler := p.scheduler
+		if r.Drop {
+			scheduler = nil
+		}
+		if r.QuietHours != nil {
+			if until, ok := r.QuietHours.until(now); ok {
+				return PolicyDecision{Outcome: PolicyDefer, Reason: ReasonQuietHours, SendAt: until}, scheduler
+			}
+		}
+		if r.Limit > 0 {
+			p.prune(now)
+			key := policyKey{rule: i, target: n.Target}
+			w := p.windows[key]
+			if w == nil || now.Sub(w.start) >= r.Per {
+				w = &policyWindow{start: now}
+				p.windows[key] = w
+			}
+			if w.sent >= r.Limit {
+				return PolicyDecision{Outcome: PolicyDefer, Reason: ReasonRateLimit, SendAt: w.start.Add(r.Per)}, scheduler
+			}
+			w.sent++
+		}
+		break
+	}
+	return PolicyDecision{Outcome: PolicySend}, nil
+}
+
+// prune expired rate limit windows once there are twice as many as after the
+// last prune. p.mu must be held.
+func (p *Policy) prune(now time.Time) {
+	if len(p.windows) < p.pruneAt {
+		return
+	}
+	for key, w := range p.windows {
+		if now.Sub(w.start) >= p.rules[key.rule].Per {
+			delete(p.windows, key)
+		}
+	}
+	p.pruneAt = 2 * len(p.windows)
+	if p.pruneAt < minPolicyPrune {
+		p.pruneAt = minPolicyPrune
+	}
+}
@@ -0,0 +1,167 @@
+package minitel
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestQuietHours(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data: ", err)
+	}
+	q := QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour, Location: berlin}
+
+	tests := []struct {
+		at    time.Time
+		quiet bool
+		until time.Time
+	}{
+		{time.Date(2026, 10, 18, 21, 59, 0, 0, berlin), false, time.Time{}},
+		{time.Date(2026, 10, 18, 23, 0, 0, 0, berlin), true, time.Date(2026, 10, 19, 7, 0, 0, 0, berlin)},
+		{time.Date(2026, 10, 19, 3, 0, 0, 0, berlin), true, time.Date(2026, 10, 19, 7, 0, 0, 0, berlin)},
+		// 23:00 UTC is 01:00 in Berlin.
+		{time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC), true, time.Date(2026, 10, 19, 7, 0, 0, 0, berlin)},
+		{time.Date(2026, 10, 19, 7, 0, 0, 0, berlin), false, time.Time{}},
+	}
+	for _, test := range tests {
+		until, quiet := q.until(test.at)
+		if quiet != test.quiet || !until.Equal(test.until) {
+			t.Errorf("at %s expected %t until %s, got %t until %s", test.at, test.quiet, test.until, quiet, until)
+		}
+	}
+
+	day := QuietHours{Start: 12 * time.Hour, End: 13 * time.Hour}
+	if until, quiet := day.until(time.Date(2026, 10, 18, 12, 30, 0, 0, time.UTC)); !quiet || until.Hour() != 13 {
+		t.Errorf("expected lunch to be quiet until 13:00, got %t until %s", quiet, until)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	clock := miniteltest.NewClock(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	f := &fakeNotifier{}
+
+	var decisions []PolicyDecision
+	p, err := NewPolicy(f, []PolicyRule{
+		{
+			Target:     Target{Type: User},
+			Exempt:     []Severity{Critical},
+			QuietHours: &QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour},
+		},
+		{
+			Target: Target{Type: App},
+			Limit:  2,
+			Per:    time.Hour,
+			Drop:   true,
+		},
+	}, WithPolicyScheduleStore(NewMemoryScheduleStore()), WithPolicyClock(clock), WithPolicyDecisionHandler(func(_ Notification, d PolicyDecision) {
+		decisions = append(decisions, d)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	app := testNotification()
+	for i := 0; i < 3; i++ {
+		if _, err := p.Notify(app); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(f.sent) != 2 || decisions[2].Outcome != PolicyDrop || decisions[2].Reason != ReasonRateLimit {
+		t.Errorf("expected the third app notification to be dropped, got %d sent and %+v", len(f.sent), decisions)
+	}
+	clock.Advance(time.Hour)
+	if d, _, _ := p.Send(app); d.Outcome != PolicySend {
+		t.Errorf("expected the rate limit to reset, got %+v", d)
+	}
+
+	clock.Advance(10 * time.Hour) // 23:00
+	user := app
+	user.Target.Type = User
+	d, _, err := p.Send(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Outcome != PolicyDefer || d.Reason != ReasonQuietHours || d.Handle == "" || d.SendAt.Hour() != 7 {
+		t.Errorf("expected the user notification to be deferred until 07:00, got %+v", d)
+	}
+
+	user.Severity = Critical
+	if d, _, _ := p.Send(user); d.Outcome != PolicySend {
+		t.Errorf("expected critical notification to be exempt, got %+v", d)
+	}
+	sent := len(f.sent)
+
+	clock.Advance(8 * time.Hour)
+	if len(f.sent) != sent+1 {
+		t.Errorf("expected the deferred notification to be sent after quiet hours, got %d sent", len(f.sent))
+	}
+}
+
+func TestPolicyRateLimitDefer(t *testing.T) {
+	clock := miniteltest.NewClock(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC))
+	f := &fakeNotifier{}
+	p, err := NewPolicy(f, []PolicyRule{{Target: Target{Type: App}, Limit: 2, Per: time.Hour}},
+		WithPolicyScheduleStore(NewMemoryScheduleStore()), WithPolicyClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	n := testNotification()
+	for i := 0; i < 5; i++ {
+		if _, err := p.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Deferred sends go back through the limit, 2 an hour.
+	for hour, want := range []int{2, 4, 5, 5} {
+		if got := len(f.sent); got != want {
+			t.Errorf("after %d hours expected %d sent, got %d", hour, want, got)
+		}
+		clock.Advance(time.Hour)
+	}
+}
+
+func TestPolicyPrunesWindows(t *testing.T) {
+	clock := miniteltest.NewClock(time.Now())
+	p, err := NewPolicy(&fakeNotifier{}, []PolicyRule{{Limit: 1, Per: time.Minute, Drop: true}}, WithPolicyClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := testNotification()
+	for i := 0; i < 10*minPolicyPrune; i++ {
+		n.Target.ID = fmt.Sprintf("93f90f07-bbe3-433d-806d-%012d", i)
+		if _, err := p.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		clock.Advance(time.Second)
+	}
+	if got := len(p.windows); got > 2*minPolicyPrune {
+		t.Errorf("expected expired windows to be pruned, got %d", got)
+	}
+}
+
+func TestPolicyInvalidRules(t *testing.T) {
+	tests := []struct {
+		rule PolicyRule
+		err  error
+	}{
+		{PolicyRule{Limit: 1}, errPolicyNoPeriod},
+		{PolicyRule{Limit: 1, Per: -time.Hour}, errPolicyNoPeriod},
+		{PolicyRule{QuietHours: &QuietHours{Start: 22 * time.Hour, End: 31 * time.Hour}}, errQuietHoursBounds},
+		{PolicyRule{QuietHours: &QuietHours{Start: -time.Hour, End: 7 * time.Hour}}, errQuietHoursBounds},
+		{PolicyRule{Limit: 1, Per: time.Hour, QuietHours: &QuietHours{Start: 0, End: 24 * time.Hour}}, nil},
+	}
+	for _, test := range tests {
+		if _, err := NewPolicy(&fakeNotifier{}, []PolicyRule{test.rule}); err != test.err {
+			t.Errorf("NewPolicy(%+v) expected %v, got %v", test.rule, test.err, err)
+		}
+	}
+}
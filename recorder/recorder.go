@@ -0,0 +1,204 @@
+// Package recorder provides http.RoundTrippers that record interactions with
+// Telex to a cassette file and replay them later, so that integration tests
+// can run deterministically without network access.
+//
+// Record once against a real Telex:
+//
+//	rec := recorder.NewRecorder("testdata/notify.json", nil)
+//	c, _ := minitel.New(url, minitel.WithHTTPClient(&http.Client{Transport: rec}))
+//
+// Then replay in CI:
+//
+//	rep, _ := recorder.NewReplayer("testdata/notify.json")
+//	c, _ := minitel.New(url, minitel.WithHTTPClient(&http.Client{Transport: rep}))
+package recorder
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// redacted replaces credentials in recorded interactions.
+const redacted = "REDACTED"
+
+// sensitiveHeaders are redacted before interactions are written.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Cassette holds recorded interactions in the order they happened.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and the response it received.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request as recorded. Bodies are stored decompressed.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Response as recorded. Bodies are stored decompressed.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is a http.RoundTripper that sends requests using its transport and
+// writes every interaction to the cassette file, replacing its contents.
+type Recorder struct {
+	path      string
+	transport http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder returns a Recorder writing to the cassette file at path, sending
+// requests using transport or http.DefaultTransport if nil.
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{path: path, transport: transport}
+}
+
+// RoundTrip sends req and records the interaction.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req = req.Clone(req.Context())
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	in, err := newInteraction(req, reqBody, resp, respBody)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	if err := writeCassette(r.path, r.cassette); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func newInteraction(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte) (Interaction, error) {
+	reqBody, reqHeader, err := decode(req.Header, reqBody)
+	if err != nil {
+		return Interaction{}, err
+	}
+	respBody, respHeader, err := decode(resp.Header, respBody)
+	if err != nil {
+		return Interaction{}, err
+	}
+
+	u := *req.URL
+	u.User = nil
+	return Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    u.String(),
+			Header: scrub(reqHeader),
+			Body:   string(reqBody),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     scrub(respHeader),
+			Body:       string(respBody),
+		},
+	}, nil
+}
+
+// decode returns body decompressed if header says it is gzip encoded, along
+// with a copy of header describing the decompressed body.
+func decode(header http.Header, body []byte) ([]byte, http.Header, error) {
+	header = header.Clone()
+	if header.Get("Content-Encoding") != "gzip" {
+		return body, header, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := ioutil.ReadAll(zr)
+	if err != nil {
+		return nil, nil, err
+	}
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+	return b, header, nil
+}
+
+func scrub(header http.Header) http.Header {
+	for _, h := range sensitiveHeaders {
+		if header.Get(h) != "" {
+			header.Set(h, redacted)
+		}
+	}
+	return header
+}
+
+// ReadCassette reads the cassette file at path.
+func ReadCassette(path string) (Cassette, error) {
+	var c Cassette
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// writeCassette replaces the file at path atomically.
+func writeCassette(path string, c Cassette) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,180 @@
+package recorder
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	minitel "github.com/heroku/minitel-go"
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+const secret = "s3cr3t-p4ss"
+
+var n = minitel.Notification{
+	Title: "Hello",
+	Body:  "DB on fire!",
+	Target: minitel.Target{
+		Type: minitel.App,
+		ID:   "93f90f07-bbe3-433d-806d-2d01bc5ae1f2",
+	},
+}
+
+// record n and a followup against a TestServer, returning the cassette path
+// and the Results received.
+func record(t *testing.T, dir string) (string, []minitel.Result) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.CompressResponses()
+	ts.ExpectNotify(miniteltest.GenerateHTTPResponse(t, "notify-id", http.StatusCreated))
+	ts.ExpectFollowup(miniteltest.GenerateHTTPResponse(t, "followup-id", http.StatusCreated))
+
+	path := filepath.Join(dir, "cassette.json")
+	rec := NewRecorder(path, nil)
+	u := strings.Replace(ts.URL, "http://", "http://user:"+secret+"@", 1)
+	c, err := minitel.New(u, minitel.WithHTTPClient(&http.Client{Transport: rec}), minitel.WithGzip(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r1, err := c.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := c.Followup(r1.ID, "Still on fire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, []minitel.Result{r1, r2}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRecord(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path, results := record(t, dir)
+	if results[0].ID != "notify-id" || results[1].ID != "followup-id" {
+		t.Fatalf("unexpected results while recording: %+v", results)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), secret) {
+		t.Errorf("cassette contains the password:\n%s", b)
+	}
+
+	c, err := ReadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Interactions) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(c.Interactions))
+	}
+	in := c.Interactions[0]
+	if got := in.Request.Header.Get("Authorization"); got != redacted {
+		t.Errorf("expected Authorization to be redacted, got %q", got)
+	}
+	if !strings.Contains(in.Request.Body, `"title":"Hello"`) || !strings.Contains(in.Response.Body, "notify-id") {
+		t.Errorf("expected decompressed bodies to be recorded, got %+v", in)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path, _ := record(t, dir)
+
+	rep, err := NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The recording server is gone, only the cassette remains.
+	c, err := minitel.New("http://telex.invalid", minitel.WithHTTPClient(&http.Client{Transport: rep}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := n
+	other.Title = "Not recorded"
+	if _, err := c.Notify(other); err == nil {
+		t.Error("expected error for unmatched request but was nil")
+	}
+
+	res, err := c.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "notify-id" {
+		t.Errorf("expected replayed ID, got %q", res.ID)
+	}
+	res, err = c.Followup("notify-id", "Still on fire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "followup-id" {
+		t.Errorf("expected replayed ID, got %q", res.ID)
+	}
+	if rep.Remaining() != 0 {
+		t.Errorf("expected every interaction to be replayed, %d remain", rep.Remaining())
+	}
+
+	if _, err := c.Notify(n); err == nil {
+		t.Error("expected error once interactions are used up but was nil")
+	}
+}
+
+func TestReplayMatchers(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path, _ := record(t, dir)
+
+	rep, err := NewReplayer(path, MatchMethod, MatchPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := minitel.New("http://telex.invalid", minitel.WithHTTPClient(&http.Client{Transport: rep}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := n
+	other.Title = "Different body, same path"
+	res, err := c.Notify(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "notify-id" {
+		t.Errorf("expected replayed ID, got %q", res.ID)
+	}
+}
+
+func TestMatchBodyJSON(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "http://telex.invalid", nil)
+	for _, tc := range []struct {
+		body, rec string
+		want      bool
+	}{
+		{`{"a":1,"b":[1,2]}`, `{"b": [1, 2], "a": 1}`, true},
+		{`{"a":1}`, `{"a":2}`, false},
+		{``, ``, true},
+		{`{}`, ``, false},
+		{`not json`, `not json`, false},
+	} {
+		if got := MatchBodyJSON(req, []byte(tc.body), Request{Body: tc.rec}); got != tc.want {
+			t.Errorf("MatchBodyJSON(%q, %q) = %t, want %t", tc.body, tc.rec, got, tc.want)
+		}
+	}
+}
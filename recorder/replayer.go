@@ -0,0 +1,124 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+)
+
+// Matcher reports whether req, whose decompressed body is body, matches the
+// recorded request rec.
+type Matcher func(req *http.Request, body []byte, rec Request) bool
+
+// MatchMethod matches requests with the same method.
+func MatchMethod(req *http.Request, body []byte, rec Request) bool {
+	return req.Method == rec.Method
+}
+
+// MatchPath matches requests with the same URL path, ignoring the host so that
+// a cassette can be replayed against any URL.
+func MatchPath(req *http.Request, body []byte, rec Request) bool {
+	u, err := url.Parse(rec.URL)
+	return err == nil && u.Path == req.URL.Path
+}
+
+// MatchBodyJSON matches requests whose bodies are equal JSON values,
+// regardless of formatting and key order. Empty bodies match each other.
+func MatchBodyJSON(req *http.Request, body []byte, rec Request) bool {
+	if len(bytes.TrimSpace(body)) == 0 || len(bytes.TrimSpace([]byte(rec.Body))) == 0 {
+		return len(bytes.TrimSpace(body)) == len(bytes.TrimSpace([]byte(rec.Body)))
+	}
+	var a, b interface{}
+	if err := json.Unmarshal(body, &a); err != nil {
+		return false
+	}
+	if err := json.Unmarshal([]byte(rec.Body), &b); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// DefaultMatchers are used by a Replayer unless others are given.
+var DefaultMatchers = []Matcher{MatchMethod, MatchPath, MatchBodyJSON}
+
+// Replayer is a http.RoundTripper that responds to requests with the
+// responses in a cassette, without any network access. Each recorded
+// interaction is replayed at most once, in the order recorded. Requests that
+// don't match any remaining interaction fail.
+type Replayer struct {
+	matchers []Matcher
+
+	mu        sync.Mutex
+	remaining []Interaction
+}
+
+// NewReplayer returns a Replayer for the cassette file at path, using matchers
+// to decide which interaction a request matches. All matchers must match.
+func NewReplayer(path string, matchers ...Matcher) (*Replayer, error) {
+	c, err := ReadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(matchers) == 0 {
+		matchers = DefaultMatchers
+	}
+	return &Replayer{matchers: matchers, remaining: c.Interactions}, nil
+}
+
+// RoundTrip responds with the first remaining interaction matching req.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if body, _, err = decode(req.Header, b); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.remaining {
+		if !r.matches(req, body, in.Request) {
+			continue
+		}
+		r.remaining = append(r.remaining[:i:i], r.remaining[i+1:]...)
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewBufferString(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("recorder: no recorded interaction matches %s %s", req.Method, req.URL.Path)
+}
+
+func (r *Replayer) matches(req *http.Request, body []byte, rec Request) bool {
+	for _, m := range r.matchers {
+		if !m(req, body, rec) {
+			return false
+		}
+	}
+	return true
+}
+
+// Remaining returns the number of interactions that haven't been replayed,
+// so tests can check every recorded request was made.
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.remaining)
+}
@@ -0,0 +1,110 @@
+package minitel
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// redacted replaces passwords in strings produced by this package.
+const redacted = "xxxxx"
+
+// String returns the Telex URL the Client is targeted at with the password
+// redacted.
+func (c *Client) String() string {
+	return "minitel.Client(" + c.redactedURL() + ")"
+}
+
+// GoString is like String, for the %#v verb.
+func (c *Client) GoString() string {
+	pass := ""
+	if c.pass != "" {
+		pass = redacted
+	}
+	return fmt.Sprintf("&minitel.Client{url:%q, user:%q, pass:%q}", c.url, c.user, pass)
+}
+
+func (c *Client) redactedURL() string {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return c.url
+	}
+	switch {
+	case c.pass != "":
+		u.User = url.UserPassword(c.user, redacted)
+	case c.user != "":
+		u.User = url.User(c.user)
+	}
+	return u.String()
+}
+
+// Redact returns err with any occurrence of the Client's password replaced.
+// Errors returned by the Client are already redacted; Redact is for errors
+// from requests made with NewRequest.
+func (c *Client) Redact(err error) error {
+	return redactError(err, c.pass)
+}
+
+// redactedError has a message with secrets removed. The wrapped error is kept
+// so that errors.Is and errors.As work, but its message may contain them.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError returns err with any occurrence of secret in its message
+// replaced. Passwords in the URL of a *url.Error are redacted too.
+func redactError(err error, secret string) error {
+	if err == nil {
+		return nil
+	}
+
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		if r := redactRawURL(ue.URL); r != ue.URL {
+			cp := *ue
+			cp.URL = r
+			if err == error(ue) {
+				err = &cp
+			} else {
+				err = &redactedError{msg: strings.Replace(err.Error(), ue.Error(), cp.Error(), -1), err: err}
+			}
+		}
+	}
+
+	if secret == "" || !strings.Contains(err.Error(), secret) {
+		return err
+	}
+	return &redactedError{msg: strings.Replace(err.Error(), secret, redacted, -1), err: err}
+}
+
+// redactRawURL replaces the password in raw, which needn't be a valid URL.
+func redactRawURL(raw string) string {
+	i := strings.Index(raw, "://")
+	if i < 0 {
+		return raw
+	}
+	start := i + len("://")
+	authority := raw[start:]
+	if end := strings.IndexAny(authority, "/?#"); end >= 0 {
+		authority = authority[:end]
+	}
+	at := strings.LastIndex(authority, "@")
+	if at < 0 {
+		return raw
+	}
+	colon := strings.Index(authority[:at], ":")
+	if colon < 0 {
+		return raw
+	}
+	return raw[:start+colon+1] + redacted + raw[start+at:]
+}
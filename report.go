@@ -0,0 +1,258 @@
+package minitel
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stackExcerptLines is the number of lines of a panic's stack trace sent in
+// the Followup.
+const stackExcerptLines = 40
+
+// ReportOption configures the middleware returned by ReportingMiddleware.
+type ReportOption func(*reporter)
+
+// ReportPanics sets whether panics are recovered and reported. Defaults to
+// true.
+func ReportPanics(report bool) ReportOption {
+	return func(r *reporter) {
+		r.panics = report
+	}
+}
+
+// ReportServerErrors sets whether responses with a 5xx status are reported.
+// Defaults to true.
+func ReportServerErrors(report bool) ReportOption {
+	return func(r *reporter) {
+		r.serverErrors = report
+	}
+}
+
+// WithReportWindow sets the window within which repeated reports of the same
+// problem are suppressed. Defaults to 5 minutes.
+func WithReportWindow(d time.Duration) ReportOption {
+	return func(r *reporter) {
+		r.window = d
+	}
+}
+
+// WithReportRate limits the number of reports sent to n per period. Defaults to
+// 10 a minute.
+func WithReportRate(n int, per time.Duration) ReportOption {
+	return func(r *reporter) {
+		r.rate, r.per = n, per
+	}
+}
+
+// WithReportClock sets the Clock used for suppression and rate limiting.
+func WithReportClock(c Clock) ReportOption {
+	return func(r *reporter) {
+		r.clock = c
+	}
+}
+
+// WithReportErrorHandler sets a func called with errors sending reports, as
+// they are sent asynchronously.
+func WithReportErrorHandler(f func(error)) ReportOption {
+	return func(r *reporter) {
+		r.onError = f
+	}
+}
+
+// reporter holds the state shared by all requests through the middleware.
+type reporter struct {
+	target       Target
+	panics       bool
+	serverErrors bool
+	window       time.Duration
+	rate         int
+	per          time.Duration
+	clock        Clock
+	onError      func(error)
+
+	mu          sync.Mutex
+	seen        *LRUDedupStore
+	periodStart time.Time
+	sent        int
+}
+
+// ReportingMiddleware returns net/http middleware that reports panics and 5xx
+// responses to target. Reports are sent asynchronously through the Notifier
+// found in the request context, see Middleware, with the stack trace of a
+// panic in a Followup. Panics are recovered and answered with a 500 unless the
+// response was already started.
+func ReportingMiddleware(target Target, opts ...ReportOption) func(http.Handler) http.Handler {
+	r := newReporter(target, opts...)
+	return r.middleware
+}
+
+func newReporter(target Target, opts ...ReportOption) *reporter {
+	r := &reporter{
+		target:       target,
+		panics:       true,
+		serverErrors: true,
+		window:       5 * time.Minute,
+		rate:         10,
+		per:          time.Minute,
+		clock:        SystemClock,
+		onError:      func(error) {},
+		seen:         NewLRUDedupStore(defaultDedupSize),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *reporter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sw := &statusWriter{ResponseWriter: w}
+		if r.panics {
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				if !sw.wroteHeader {
+					http.Error(sw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+				r.report(req, fmt.Sprintf("panic:%s %s:%v", req.Method, req.URL.Path, p), Notification{
+					Title:    fmt.Sprintf("Panic in %s %s", req.Method, req.URL.Path),
+					Body:     fmt.Sprintf("%v", p),
+					Severity: Critical,
+				}, stackExcerpt(debug.Stack()))
+			}()
+		}
+
+		next.ServeHTTP(sw, req)
+
+		if r.serverErrors && sw.status >= 500 {
+			r.report(req, fmt.Sprintf("status:%s %s:%d", req.Method, req.URL.Path, sw.status), Notification{
+				Title:    fmt.Sprintf("%d response to %s %s", sw.status, req.Method, req.URL.Path),
+				Body:     fmt.Sprintf("%s %s responded with %d %s.", req.Method, req.URL.Path, sw.status, http.StatusText(sw.status)),
+				Severity: Warning,
+			}, "")
+		}
+	})
+}
+
+// report sends n, followed by followup if set, unless the problem identified
+// by key was reported within the window or the rate limit is reached.
+func (r *reporter) report(req *http.Request, key string, n Notification, followup string) {
+	if !r.allow(key) {
+		return
+	}
+	n.Target = r.target
+	notifier := NotifierFromContext(req.Context())
+
+	go func() {
+		res, err := notifier.Notify(n)
+		if err != nil {
+			r.onError(err)
+			return
+		}
+		if followup == "" || res.ID == "" {
+			return
+		}
+		if _, err := notifier.Followup(res.ID, followup); err != nil {
+			r.onError(err)
+		}
+	}()
+}
+
+func (r *reporter) allow(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.clock.Now()
+	if e, ok := r.seen.Get(key); ok && now.Sub(e.Sent) < r.window {
+		return false
+	}
+	if now.Sub(r.periodStart) >= r.per {
+		r.periodStart, r.sent = now, 0
+	}
+	if r.sent >= r.rate {
+		return false
+	}
+	r.sent++
+	r.seen.Put(key, DedupEntry{Sent: now})
+	return true
+}
+
+func stackExcerpt(stack []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	if len(lines) > stackExcerptLines {
+		lines = append(lines[:stackExcerptLines], "...")
+	}
+	return strings.Join(lines, "\n")
+}
+
+var errHijackUnsupported = errors.New("minitel: ResponseWriter does not support hijacking")
+
+// statusWriter records the status code of a response. It forwards Flush,
+// Hijack and Push to the wrapped ResponseWriter where it supports them, so
+// that streaming and websocket handlers keep working behind the middleware.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = http.StatusOK, true
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush the response if the wrapped ResponseWriter supports it.
+func (w *statusWriter) Flush() {
+	f, ok := w.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = http.StatusOK, true
+	}
+	f.Flush()
+}
+
+// Hijack the connection if the wrapped ResponseWriter supports it.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errHijackUnsupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		// The response is the handler's to write now.
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Push target if the wrapped ResponseWriter supports HTTP/2 server push.
+func (w *statusWriter) Push(target string, opts *http.PushOptions) error {
+	p, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
@@ -0,0 +1,171 @@
+package minitel
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+var reportTarget = Target{Type: App, ID: "93f90f07-bbe3-433d-806d-2d01bc5ae1f2"}
+
+// serve requests to path through h with f as the Notifier in context.
+func serve(r *reporter, f Notifier, h http.HandlerFunc, paths ...string) []int {
+	handler := Middleware(f)(r.middleware(h))
+	var codes []int
+	for _, p := range paths {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, p, nil))
+		codes = append(codes, w.Code)
+	}
+	return codes
+}
+
+// waitReports waits for f to have been sent the given number of reports and
+// followups, as they are sent asynchronously.
+func waitReports(t *testing.T, f *fakeNotifier, sent, followups int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		f.mu.Lock()
+		gotSent, gotFollowups := len(f.sent), len(f.followups)
+		f.mu.Unlock()
+		if gotSent == sent && gotFollowups == followups {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d reports and %d followups, got %d and %d", sent, followups, gotSent, gotFollowups)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReportPanic(t *testing.T) {
+	f := &fakeNotifier{}
+	r := newReporter(reportTarget)
+
+	codes := serve(r, f, func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	}, "/internal")
+
+	if codes[0] != http.StatusInternalServerError {
+		t.Errorf("expected recovered panic to respond 500, got %d", codes[0])
+	}
+	waitReports(t, f, 1, 1)
+	n := f.sent[0]
+	if n.Target != reportTarget || n.Severity != Critical || n.Title != "Panic in GET /internal" || n.Body != "boom" {
+		t.Errorf("unexpected report: %+v", n)
+	}
+	if len(f.followups) != 1 || !strings.Contains(f.followups[0], "goroutine") {
+		t.Errorf("expected stack excerpt followup, got %q", f.followups)
+	}
+}
+
+func TestReportServerErrors(t *testing.T) {
+	f := &fakeNotifier{}
+	r := newReporter(reportTarget, ReportPanics(false))
+
+	serve(r, f, func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/ok" {
+			w.Write([]byte("ok"))
+			return
+		}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}, "/ok", "/down")
+
+	waitReports(t, f, 1, 0)
+	if n := f.sent[0]; n.Title != "503 response to GET /down" || n.Severity != Warning {
+		t.Errorf("unexpected report: %+v", n)
+	}
+}
+
+func TestReportDedupAndRate(t *testing.T) {
+	f := &fakeNotifier{}
+	clock := miniteltest.NewClock(time.Now())
+	r := newReporter(reportTarget, WithReportClock(clock), WithReportWindow(time.Minute), WithReportRate(2, time.Hour))
+	h := func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+
+	// Suppression is decided before reports are sent, so none are pending
+	// once the expected number arrive.
+	serve(r, f, h, "/a", "/a", "/a")
+	waitReports(t, f, 1, 0)
+
+	// The rate limit allows 2 reports an hour.
+	serve(r, f, h, "/b", "/c")
+	waitReports(t, f, 2, 0)
+
+	clock.Advance(time.Hour)
+	serve(r, f, h, "/c")
+	waitReports(t, f, 3, 0)
+}
+
+func TestReportStreaming(t *testing.T) {
+	r := newReporter(reportTarget)
+	f := &fakeNotifier{}
+
+	serve(r, f, func(w http.ResponseWriter, req *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("expected the ResponseWriter to be a http.Flusher")
+		}
+		w.Write([]byte("data: 1\n\n"))
+		flusher.Flush()
+		if _, _, err := w.(http.Hijacker).Hijack(); err != errHijackUnsupported {
+			t.Errorf("expected hijacking a recorder to be unsupported, got %v", err)
+		}
+		if err := w.(http.Pusher).Push("/style.css", nil); err != http.ErrNotSupported {
+			t.Errorf("expected push to be unsupported, got %v", err)
+		}
+	}, "/events")
+
+	ts := httptest.NewServer(Middleware(f)(r.middleware(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\nConnection: close\r\n\r\nok")
+		rw.Flush()
+		panic("after hijacking")
+	}))))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Errorf("expected the hijacked response, got %d %q", resp.StatusCode, body)
+	}
+	// The panic is still reported.
+	waitReports(t, f, 1, 1)
+}
+
+func TestReportingMiddlewareWithClient(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+	ts.ExpectFollowup(nil)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := Middleware(c)(ReportingMiddleware(reportTarget)(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("boom")
+	})))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if finished := ts.ExpectDone(time.Second); !finished {
+		t.Error("expected the report and followup to be sent")
+	}
+}
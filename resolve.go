@@ -0,0 +1,238 @@
+package minitel
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrTargetNotFound is returned by a Resolver when no Target has the given
+// identifier.
+var ErrTargetNotFound = errors.New("minitel: Target not found")
+
+var errUnresolvableType = errors.New("minitel: Target.Type can't be resolved")
+
+// Resolver maps a human identifier of a Target, such as an app name or a
+// user's email address, to its ID.
+type Resolver interface {
+	Resolve(t Type, identifier string) (id string, err error)
+}
+
+// ResolvingNotifier resolves the Target ID of each Notification before sending
+// it, unless the ID is already a UUID.
+type ResolvingNotifier struct {
+	Notifier
+	resolver Resolver
+}
+
+// NewResolvingNotifier returns a ResolvingNotifier resolving with r and
+// sending through n.
+func NewResolvingNotifier(n Notifier, r Resolver) *ResolvingNotifier {
+	return &ResolvingNotifier{Notifier: n, resolver: r}
+}
+
+// Notify resolves the Target ID of n and sends it.
+func (rn *ResolvingNotifier) Notify(n Notification) (Result, error) {
+	if n.Target.ID != "" {
+		if _, err := uuid.Parse(n.Target.ID); err != nil {
+			id, err := rn.resolver.Resolve(n.Target.Type, n.Target.ID)
+			if err != nil {
+				return Result{}, err
+			}
+			n.Target.ID = id
+		}
+	}
+	return rn.Notifier.Notify(n)
+}
+
+// ResolverOption configures a CachingResolver.
+type ResolverOption func(*CachingResolver)
+
+// WithResolverClock sets the Clock used to expire cached IDs.
+func WithResolverClock(c Clock) ResolverOption {
+	return func(r *CachingResolver) {
+		r.clock = c
+	}
+}
+
+// resolved is a cached Resolve result, err is nil or ErrTargetNotFound.
+type resolved struct {
+	id      string
+	err     error
+	expires time.Time
+}
+
+type resolveKey struct {
+	t          Type
+	identifier string
+}
+
+// resolveCall is a lookup in progress that concurrent misses wait on.
+type resolveCall struct {
+	done chan struct{}
+	id   string
+	err  error
+}
+
+// minResolverPrune is the number of cached IDs kept before expired ones are
+// pruned.
+const minResolverPrune = 64
+
+// CachingResolver caches the IDs another Resolver returns for ttl, and that a
+// Target was not found for negativeTTL. Other errors are not cached.
+// Concurrent misses for the same identifier share one lookup.
+type CachingResolver struct {
+	r           Resolver
+	ttl         time.Duration
+	negativeTTL time.Duration
+	clock       Clock
+
+	mu       sync.Mutex
+	cache    map[resolveKey]resolved
+	pruneAt  int
+	inflight map[resolveKey]*resolveCall
+}
+
+// NewCachingResolver returns a CachingResolver in front of r.
+func NewCachingResolver(r Resolver, ttl, negativeTTL time.Duration, opts ...ResolverOption) *CachingResolver {
+	cr := &CachingResolver{
+		r:           r,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		clock:       SystemClock,
+		cache:       make(map[resolveKey]resolved),
+		pruneAt:     minResolverPrune,
+		inflight:    make(map[resolveKey]*resolveCall),
+	}
+	for _, opt := range opts {
+		opt(cr)
+	}
+	return cr
+}
+
+// Resolve identifier from the cache, or with the underlying Resolver.
+func (cr *CachingResolver) Resolve(t Type, identifier string) (string, error) {
+	key := resolveKey{t: t, identifier: identifier}
+
+	cr.mu.Lock()
+	if e, ok := cr.cache[key]; ok && cr.clock.Now().Before(e.expires) {
+		cr.mu.Unlock()
+		return e.id, e.err
+	}
+	if call, ok := cr.inflight[key]; ok {
+		cr.mu.Unlock()
+		<-call.done
+		return call.id, call.err
+	}
+	call := &resolveCall{done: make(chan struct{})}
+	cr.inflight[key] = call
+	cr.mu.Unlock()
+
+	call.id, call.err = cr.r.Resolve(t, identifier)
+
+	cr.mu.Lock()
+	delete(cr.inflight, key)
+	now := cr.clock.Now()
+	switch {
+	case call.err == nil:
+		cr.prune(now)
+		cr.cache[key] = resolved{id: call.id, expires: now.Add(cr.ttl)}
+	case call.err == ErrTargetNotFound:
+		cr.prune(now)
+		cr.cache[key] = resolved{err: call.err, expires: now.Add(cr.negativeTTL)}
+	}
+	cr.mu.Unlock()
+	close(call.done)
+
+	return call.id, call.err
+}
+
+// prune expired entries once there are twice as many as after the last prune.
+// cr.mu must be held.
+func (cr *CachingResolver) prune(now time.Time) {
+	if len(cr.cache) < cr.pruneAt {
+		return
+	}
+	for k, e := range cr.cache {
+		if !now.Before(e.expires) {
+			delete(cr.cache, k)
+		}
+	}
+	cr.pruneAt = 2 * len(cr.cache)
+	if cr.pruneAt < minResolverPrune {
+		cr.pruneAt = minResolverPrune
+	}
+}
+
+// HTTPResolver resolves app names and user email addresses with the Heroku
+// Platform API, or anything serving the same GET /apps/{name} and
+// GET /users/{email} endpoints.
+type HTTPResolver struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPResolver returns a HTTPResolver for the API at baseURL, e.g.
+// https://api.heroku.com, authenticating with token. It uses client, or
+// http.DefaultClient if client is nil.
+func NewHTTPResolver(baseURL, token string, client *http.Client) *HTTPResolver {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPResolver{url: strings.TrimSuffix(baseURL, "/"), token: token, client: client}
+}
+
+// Resolve the app name or user email address identifier.
+func (h *HTTPResolver) Resolve(t Type, identifier string) (string, error) {
+	var path string
+	switch t {
+	case App:
+		path = "/apps/"
+	case User:
+		path = "/users/"
+	default:
+		return "", errUnresolvableType
+	}
+
+	req, err := http.NewRequest(http.MethodGet, h.url+path+url.PathEscape(identifier), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.heroku+json; version=3")
+	if h.token != "" {
+		req.Header.Set("Authorization", "Bearer "+h.token)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", ErrTargetNotFound
+	default:
+		return "", &StatusError{Expected: http.StatusOK, Got: resp.StatusCode}
+	}
+
+	var body struct {
+		ID string `json:"id"`
+	}
+	dec := json.NewDecoder(resp.Body)
+	if err := dec.Decode(&body); err != nil {
+		return "", err
+	}
+	if _, err := uuid.Parse(body.ID); err != nil {
+		return "", errIDNotUUID
+	}
+	return body.ID, nil
+}
@@ -0,0 +1,201 @@
+package minitel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+const (
+	appUUID  = "93f90f07-bbe3-433d-806d-2d01bc5ae1f2"
+	userUUID = "bc31ed62-0204-40e5-86cf-b25a001b20db"
+)
+
+// platformAPI stands in for the Heroku Platform API, counting the lookups it
+// serves.
+type platformAPI struct {
+	*httptest.Server
+	mu      sync.Mutex
+	lookups int
+}
+
+func newPlatformAPI() *platformAPI {
+	api := &platformAPI{}
+	ids := map[string]string{
+		"/apps/example":          appUUID,
+		"/users/user@heroku.com": userUUID,
+	}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api.mu.Lock()
+		api.lookups++
+		api.mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer token" || !strings.Contains(r.Header.Get("Accept"), "version=3") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		id, ok := ids[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": id})
+	}))
+	return api
+}
+
+func (api *platformAPI) Lookups() int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return api.lookups
+}
+
+func TestHTTPResolver(t *testing.T) {
+	api := newPlatformAPI()
+	defer api.Close()
+	r := NewHTTPResolver(api.URL, "token", nil)
+
+	tests := []struct {
+		t          Type
+		identifier string
+		id         string
+		err        error
+	}{
+		{App, "example", appUUID, nil},
+		{User, "user@heroku.com", userUUID, nil},
+		{App, "missing", "", ErrTargetNotFound},
+		{Type("team"), "example", "", errUnresolvableType},
+	}
+	for _, test := range tests {
+		id, err := r.Resolve(test.t, test.identifier)
+		if id != test.id || err != test.err {
+			t.Errorf("Resolve(%s, %q) = %q, %v, want %q, %v", test.t, test.identifier, id, err, test.id, test.err)
+		}
+	}
+
+	_, err := NewHTTPResolver(api.URL, "wrong", nil).Resolve(App, "example")
+	if se, ok := err.(*StatusError); !ok || se.Got != http.StatusUnauthorized {
+		t.Errorf("expected a StatusError for a 401, got %v", err)
+	}
+}
+
+func TestCachingResolver(t *testing.T) {
+	api := newPlatformAPI()
+	defer api.Close()
+	clock := miniteltest.NewClock(time.Now())
+	r := NewCachingResolver(NewHTTPResolver(api.URL, "token", nil), time.Hour, time.Minute, WithResolverClock(clock))
+
+	for i := 0; i < 2; i++ {
+		if id, err := r.Resolve(App, "example"); err != nil || id != appUUID {
+			t.Fatalf("unexpected resolution: %q, %v", id, err)
+		}
+		if _, err := r.Resolve(App, "missing"); err != ErrTargetNotFound {
+			t.Fatalf("expected ErrTargetNotFound, got %v", err)
+		}
+	}
+	if got := api.Lookups(); got != 2 {
+		t.Errorf("expected repeated resolutions to be cached, got %d lookups", got)
+	}
+
+	clock.Advance(time.Minute)
+	r.Resolve(App, "example")
+	r.Resolve(App, "missing")
+	if got := api.Lookups(); got != 3 {
+		t.Errorf("expected only the negative entry to expire, got %d lookups", got)
+	}
+
+	clock.Advance(time.Hour)
+	r.Resolve(App, "example")
+	if got := api.Lookups(); got != 4 {
+		t.Errorf("expected the entry to expire, got %d lookups", got)
+	}
+}
+
+func TestResolvingNotifier(t *testing.T) {
+	api := newPlatformAPI()
+	defer api.Close()
+	f := &fakeNotifier{}
+	rn := NewResolvingNotifier(f, NewHTTPResolver(api.URL, "token", nil))
+
+	n := testNotification()
+	n.Target = Target{Type: User, ID: "user@heroku.com"}
+	if _, err := rn.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	n.Target = Target{Type: App, ID: appUUID}
+	if _, err := rn.Notify(n); err != nil {
+		t.Fatal(err)
+	}
+	n.Target = Target{Type: App, ID: "missing"}
+	if _, err := rn.Notify(n); err != ErrTargetNotFound {
+		t.Errorf("expected ErrTargetNotFound, got %v", err)
+	}
+
+	if len(f.sent) != 2 || f.sent[0].Target.ID != userUUID || f.sent[1].Target.ID != appUUID {
+		t.Errorf("unexpected notifications sent: %+v", f.sent)
+	}
+	if got := api.Lookups(); got != 2 {
+		t.Errorf("expected UUIDs not to be looked up, got %d lookups", got)
+	}
+}
+
+// slowResolver resolves every identifier to appUUID once release is closed,
+// counting lookups.
+type slowResolver struct {
+	release chan struct{}
+	mu      sync.Mutex
+	lookups int
+}
+
+func (r *slowResolver) Resolve(Type, string) (string, error) {
+	r.mu.Lock()
+	r.lookups++
+	r.mu.Unlock()
+	<-r.release
+	return appUUID, nil
+}
+
+func TestCachingResolverConcurrentMisses(t *testing.T) {
+	slow := &slowResolver{release: make(chan struct{})}
+	r := NewCachingResolver(slow, time.Hour, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if id, err := r.Resolve(App, "example"); err != nil || id != appUUID {
+				t.Errorf("unexpected resolution: %q, %v", id, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(slow.release)
+	wg.Wait()
+
+	if slow.lookups != 1 {
+		t.Errorf("expected concurrent misses to share a lookup, got %d", slow.lookups)
+	}
+}
+
+func TestCachingResolverPrunes(t *testing.T) {
+	slow := &slowResolver{release: make(chan struct{})}
+	close(slow.release)
+	clock := miniteltest.NewClock(time.Now())
+	r := NewCachingResolver(slow, time.Minute, time.Minute, WithResolverClock(clock))
+
+	for i := 0; i < 10*minResolverPrune; i++ {
+		r.Resolve(App, fmt.Sprintf("app-%d", i))
+		clock.Advance(time.Second)
+	}
+	if got := len(r.cache); got > 2*minResolverPrune {
+		t.Errorf("expected expired entries to be pruned, got %d", got)
+	}
+}
@@ -0,0 +1,364 @@
+package minitel
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	errUnknownHandle    = errors.New("minitel: Unknown scheduled notification handle")
+	errSchedulerClosed  = errors.New("minitel: Scheduler is closed")
+	errDuplicateHandle  = errors.New("minitel: Duplicate scheduled notification handle")
+	errStoreHandleEmpty = errors.New("minitel: Scheduled notification has no handle")
+)
+
+// ScheduledNotification is a Notification waiting to be sent at SendAt.
+type ScheduledNotification struct {
+	Handle       string       `json:"handle"`
+	SendAt       time.Time    `json:"send_at"`
+	Notification Notification `json:"notification"`
+}
+
+// ScheduleStore persists the Notifications waiting to be sent by a Scheduler
+// so that they survive restarts.
+type ScheduleStore interface {
+	Add(s ScheduledNotification) error
+	Remove(handle string) error
+	List() ([]ScheduledNotification, error)
+}
+
+// ScheduleOption configures a Scheduler.
+type ScheduleOption func(*Scheduler)
+
+// WithScheduleClock sets the Clock used to decide when to send.
+func WithScheduleClock(c Clock) ScheduleOption {
+	return func(s *Scheduler) {
+		s.clock = c
+	}
+}
+
+// WithDeliveryHandler sets a func called with the outcome of every attempt to
+// send a scheduled Notification.
+func WithDeliveryHandler(f func(handle string, r Result, err error)) ScheduleOption {
+	return func(s *Scheduler) {
+		s.onDelivery = f
+	}
+}
+
+// WithScheduleRetry sets the backoff before retrying a Notification that failed
+// to send with an error that IsRetryable, doubled for each retry after it up to
+// max. Defaults to a minute and an hour.
+func WithScheduleRetry(backoff, max time.Duration) ScheduleOption {
+	return func(s *Scheduler) {
+		s.backoff, s.maxBackoff = backoff, max
+	}
+}
+
+// Scheduler sends Notifications at a later time. Notifications are removed
+// from the store once sent. Those that fail with an error that IsRetryable are
+// retried with a backoff until sent or cancelled; others remain in the store,
+// and can be cancelled, until they are retried when a Scheduler is next
+// created with it.
+type Scheduler struct {
+	n          Notifier
+	store      ScheduleStore
+	clock      Clock
+	onDelivery func(handle string, r Result, err error)
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	mu     sync.Mutex
+	timers map[string]func() bool
+	closed bool
+}
+
+// stopped is the timer of a Notification being sent or that failed to send,
+// which is kept so that it can still be cancelled.
+func stopped() bool { return false }
+
+// NewScheduler returns a Scheduler sending through n, picking up any
+// Notifications already in store. Those that are overdue are sent right away.
+func NewScheduler(n Notifier, store ScheduleStore, opts ...ScheduleOption) (*Scheduler, error) {
+	s := &Scheduler{
+		n:          n,
+		store:      store,
+		clock:      SystemClock,
+		onDelivery: func(string, Result, error) {},
+		backoff:    time.Minute,
+		maxBackoff: time.Hour,
+		timers:     make(map[string]func() bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	pending, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sn := range pending {
+		s.arm(sn)
+	}
+	return s, nil
+}
+
+// Schedule n to be sent at at, returning a handle that can be used to Cancel
+// it.
+func (s *Scheduler) Schedule(at time.Time, n Notification) (string, error) {
+	if err := n.Validate(); err != nil {
+		return "", err
+	}
+
+	sn := ScheduledNotification{
+		Handle:       uuid.New().String(),
+		SendAt:       at,
+		Notification: n,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return "", errSchedulerClosed
+	}
+	if err := s.store.Add(sn); err != nil {
+		return "", err
+	}
+	s.arm(sn)
+	return sn.Handle, nil
+}
+
+// Cancel the scheduled Notification identified by handle. One that is already
+// being sent is removed from the store but not stopped.
+func (s *Scheduler) Cancel(handle string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stop, ok := s.timers[handle]
+	if !ok {
+		return errUnknownHandle
+	}
+	stop()
+	delete(s.timers, handle)
+	return s.store.Remove(handle)
+}
+
+// Close stops sending Notifications. Those pending remain in the store.
+func (s *Scheduler) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for handle, stop := range s.timers {
+		stop()
+		delete(s.timers, handle)
+	}
+	return nil
+}
+
+// arm a timer to deliver sn. s.mu must be held.
+func (s *Scheduler) arm(sn ScheduledNotification) {
+	s.armIn(sn, sn.SendAt.Sub(s.clock.Now()), s.backoff)
+}
+
+// armIn arms a timer to deliver sn after d, retrying after backoff if that
+// fails. s.mu must be held.
+func (s *Scheduler) armIn(sn ScheduledNotification, d, backoff time.Duration) {
+	s.timers[sn.Handle] = s.clock.AfterFunc(d, func() {
+		s.deliver(sn, backoff)
+	})
+}
+
+func (s *Scheduler) deliver(sn ScheduledNotification, backoff time.Duration) {
+	s.mu.Lock()
+	if _, ok := s.timers[sn.Handle]; !ok {
+		// Cancelled or closed while the timer fired.
+		s.mu.Unlock()
+		return
+	}
+	s.timers[sn.Handle] = stopped
+	s.mu.Unlock()
+
+	r, err := s.n.Notify(sn.Notification)
+	s.mu.Lock()
+	switch {
+	case err == nil:
+		delete(s.timers, sn.Handle)
+		err = s.store.Remove(sn.Handle)
+	case IsRetryable(err):
+		// Unless cancelled or closed while sending.
+		if _, ok := s.timers[sn.Handle]; ok {
+			next := 2 * backoff
+			if next > s.maxBackoff {
+				next = s.maxBackoff
+			}
+			s.armIn(sn, backoff, next)
+		}
+	}
+	s.mu.Unlock()
+	s.onDelivery(sn.Handle, r, err)
+}
+
+// MemoryScheduleStore is a ScheduleStore that keeps Notifications in memory,
+// for when they don't need to survive restarts.
+type MemoryScheduleStore struct {
+	mu      sync.Mutex
+	pending map[string]ScheduledNotification
+}
+
+// NewMemoryScheduleStore returns an empty MemoryScheduleStore.
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{pending: make(map[string]ScheduledNotification)}
+}
+
+// Add sn to the store.
+func (m *MemoryScheduleStore) Add(sn ScheduledNotification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return addScheduled(m.pending, sn)
+}
+
+// Remove the Notification identified by handle from the store.
+func (m *MemoryScheduleStore) Remove(handle string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pending, handle)
+	return nil
+}
+
+// List the Notifications in the store.
+func (m *MemoryScheduleStore) List() ([]ScheduledNotification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return listScheduled(m.pending), nil
+}
+
+// FileScheduleStore is a ScheduleStore that keeps Notifications in a JSON
+// file. The file is replaced atomically on every change.
+type FileScheduleStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileScheduleStore returns a FileScheduleStore using the file at path,
+// which is created when first needed.
+func NewFileScheduleStore(path string) *FileScheduleStore {
+	return &FileScheduleStore{path: path}
+}
+
+// Add sn to the store.
+func (f *FileScheduleStore) Add(sn ScheduledNotification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pending, err := f.read()
+	if err != nil {
+		return err
+	}
+	if err := addScheduled(pending, sn); err != nil {
+		return err
+	}
+	return f.write(pending)
+}
+
+// Remove the Notification identified by handle from the store.
+func (f *FileScheduleStore) Remove(handle string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pending, err := f.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := pending[handle]; !ok {
+		return nil
+	}
+	delete(pending, handle)
+	return f.write(pending)
+}
+
+// List the Notifications in the store.
+func (f *FileScheduleStore) List() ([]ScheduledNotification, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pending, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return listScheduled(pending), nil
+}
+
+func (f *FileScheduleStore) read() (map[string]ScheduledNotification, error) {
+	pending := make(map[string]ScheduledNotification)
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return pending, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var list []ScheduledNotification
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, err
+	}
+	for _, sn := range list {
+		pending[sn.Handle] = sn
+	}
+	return pending, nil
+}
+
+func (f *FileScheduleStore) write(pending map[string]ScheduledNotification) error {
+	b, err := json.Marshal(listScheduled(pending))
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.path, b)
+}
+
+// writeFileAtomic replaces the file at path with b, so that readers never see
+// a partially written file.
+func writeFileAtomic(path string, b []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func addScheduled(pending map[string]ScheduledNotification, sn ScheduledNotification) error {
+	if sn.Handle == "" {
+		return errStoreHandleEmpty
+	}
+	if _, ok := pending[sn.Handle]; ok {
+		return errDuplicateHandle
+	}
+	pending[sn.Handle] = sn
+	return nil
+}
+
+func listScheduled(pending map[string]ScheduledNotification) []ScheduledNotification {
+	list := make([]ScheduledNotification, 0, len(pending))
+	for _, sn := range pending {
+		list = append(list, sn)
+	}
+	return list
+}
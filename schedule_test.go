@@ -0,0 +1,233 @@
+package minitel
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestScheduler(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := miniteltest.NewClock(time.Now())
+	store := NewMemoryScheduleStore()
+
+	var delivered []string
+	s, err := NewScheduler(c, store, WithScheduleClock(clock), WithDeliveryHandler(func(handle string, r Result, err error) {
+		if err != nil {
+			t.Error("unexpected error: ", err)
+		}
+		delivered = append(delivered, handle)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ns := testNotifications(2)
+	ns[0].Title = "Your trial ends tomorrow"
+	ns[1].Title = "Cancelled"
+	sent, err := s.Schedule(clock.Now().Add(time.Hour), ns[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := s.Schedule(clock.Now().Add(time.Hour), ns[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Cancel(cancelled); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Cancel(cancelled); err != errUnknownHandle {
+		t.Errorf("expected errUnknownHandle cancelling twice, got %v", err)
+	}
+
+	clock.Advance(59 * time.Minute)
+	if len(delivered) != 0 {
+		t.Fatalf("expected nothing delivered early, got %v", delivered)
+	}
+	clock.Advance(time.Minute)
+	if len(delivered) != 1 || delivered[0] != sent {
+		t.Fatalf("expected %q to be delivered, got %v", sent, delivered)
+	}
+
+	got := ts.Notifications()
+	if len(got) != 1 || got[0].Title != "Your trial ends tomorrow" {
+		t.Errorf("unexpected notifications sent: %+v", got)
+	}
+	if pending, _ := store.List(); len(pending) != 0 {
+		t.Errorf("expected store to be empty, got %+v", pending)
+	}
+}
+
+func TestSchedulerRestart(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "minitel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "schedule.json")
+
+	clock := miniteltest.NewClock(time.Now())
+	s, err := NewScheduler(c, NewFileScheduleStore(path), WithScheduleClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle, err := s.Schedule(clock.Now().Add(time.Hour), testNotification())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// Nothing is sent by a closed Scheduler.
+	clock.Advance(2 * time.Hour)
+	if got := len(ts.Notifications()); got != 0 {
+		t.Fatalf("expected nothing sent after Close, got %d", got)
+	}
+
+	var delivered string
+	s, err = NewScheduler(c, NewFileScheduleStore(path), WithScheduleClock(clock), WithDeliveryHandler(func(h string, r Result, err error) {
+		if err != nil {
+			t.Error("unexpected error: ", err)
+		}
+		delivered = h
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	clock.Advance(0)
+	if delivered != handle {
+		t.Errorf("expected overdue %q to be delivered after restart, got %q", handle, delivered)
+	}
+	if pending, _ := NewFileScheduleStore(path).List(); len(pending) != 0 {
+		t.Errorf("expected store to be empty, got %+v", pending)
+	}
+}
+
+func TestSchedulerCancelFailed(t *testing.T) {
+	f := &fakeNotifier{err: errors.New("unavailable")}
+	clock := miniteltest.NewClock(time.Now())
+	store := NewMemoryScheduleStore()
+
+	var failed int
+	s, err := NewScheduler(f, store, WithScheduleClock(clock), WithDeliveryHandler(func(_ string, _ Result, err error) {
+		if err != nil {
+			failed++
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	handle, err := s.Schedule(clock.Now().Add(time.Hour), testNotification())
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	if failed != 1 {
+		t.Fatalf("expected a failed delivery, got %d", failed)
+	}
+	if pending, _ := store.List(); len(pending) != 1 {
+		t.Fatalf("expected the failed notification to remain in the store, got %+v", pending)
+	}
+
+	if err := s.Cancel(handle); err != nil {
+		t.Fatalf("expected the failed notification to be cancellable, got %v", err)
+	}
+	if pending, _ := store.List(); len(pending) != 0 {
+		t.Errorf("expected store to be empty, got %+v", pending)
+	}
+}
+
+func TestSchedulerRetry(t *testing.T) {
+	f := &fakeNotifier{err: &StatusError{Expected: http.StatusCreated, Got: http.StatusServiceUnavailable}}
+	clock := miniteltest.NewClock(time.Now())
+	store := NewMemoryScheduleStore()
+
+	var attempts int
+	s, err := NewScheduler(f, store, WithScheduleClock(clock), WithScheduleRetry(time.Minute, 2*time.Minute),
+		WithDeliveryHandler(func(string, Result, error) { attempts++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if _, err := s.Schedule(clock.Now().Add(time.Hour), testNotification()); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	clock.Advance(time.Minute)
+	clock.Advance(2 * time.Minute)
+	if attempts != 3 {
+		t.Fatalf("expected the failed delivery to be retried twice, got %d attempts", attempts)
+	}
+
+	// The backoff is capped at 2 minutes.
+	f.err = nil
+	clock.Advance(2 * time.Minute)
+	if attempts != 4 || len(f.sent) != 4 {
+		t.Fatalf("expected the retry to be sent, got %d attempts", attempts)
+	}
+	if pending, _ := store.List(); len(pending) != 0 {
+		t.Errorf("expected store to be empty, got %+v", pending)
+	}
+}
+
+func TestFileScheduleStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minitel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := NewFileScheduleStore(filepath.Join(dir, "schedule.json"))
+	if pending, err := f.List(); err != nil || len(pending) != 0 {
+		t.Fatalf("expected empty store, got %v, %v", pending, err)
+	}
+
+	sn := ScheduledNotification{Handle: "h1", SendAt: time.Now().UTC(), Notification: testNotification()}
+	if err := f.Add(sn); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Add(sn); err != errDuplicateHandle {
+		t.Errorf("expected errDuplicateHandle, got %v", err)
+	}
+
+	pending, err := f.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].Handle != "h1" || !pending[0].SendAt.Equal(sn.SendAt) || pending[0].Notification.Title != "Hello" {
+		t.Errorf("unexpected pending notifications: %+v", pending)
+	}
+
+	if err := f.Remove("h1"); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := f.List(); len(pending) != 0 {
+		t.Errorf("expected empty store, got %+v", pending)
+	}
+}
@@ -0,0 +1,220 @@
+package minitel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Sink is somewhere Notifications can be sent. Client is the Sink for Telex;
+// WebhookSink, WriterSink and FileSink send copies elsewhere, for example for
+// auditing.
+type Sink interface {
+	Notify(Notification) (Result, error)
+}
+
+// WebhookSink POSTs Notifications as JSON to a URL. A 2xx response is a
+// success; if it has a JSON body it is decoded into the Result.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink returns a WebhookSink posting to url with client, or
+// http.DefaultClient if client is nil.
+func NewWebhookSink(url string, client *http.Client) *WebhookSink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &WebhookSink{url: url, client: client}
+}
+
+// Notify posts n to the webhook.
+func (s *WebhookSink) Notify(n Notification) (result Result, err error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(n); err != nil {
+		return result, err
+	}
+
+	resp, err := s.client.Post(s.url, "application/json", &buf)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, &StatusError{Expected: http.StatusOK, Got: resp.StatusCode}
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return result, nil
+	}
+
+	dec := json.NewDecoder(resp.Body)
+	if err := dec.Decode(&result); err != nil && err != io.EOF {
+		return result, err
+	}
+	return result, nil
+}
+
+// WriterSink writes Notifications to an io.Writer as JSON, one per line.
+type WriterSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriterSink returns a WriterSink writing to w.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{enc: json.NewEncoder(w)}
+}
+
+// StdoutSink returns a WriterSink writing to standard output.
+func StdoutSink() *WriterSink {
+	return NewWriterSink(os.Stdout)
+}
+
+// Notify writes n as a line of JSON.
+func (s *WriterSink) Notify(n Notification) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Result{}, s.enc.Encode(n)
+}
+
+// FileSink appends Notifications to a JSONL file.
+type FileSink struct {
+	*WriterSink
+	f *os.File
+}
+
+// NewFileSink returns a FileSink appending to the file at path, which is
+// created if it doesn't exist.
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{WriterSink: NewWriterSink(f), f: f}, nil
+}
+
+// Close the file.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// Route sends the Notifications it matches to Sink. A Route with no Types
+// matches every Target Type, one with no MinSeverity every Severity.
+type Route struct {
+	Name        string
+	Sink        Sink
+	Types       []Type
+	MinSeverity Severity
+}
+
+func (r Route) matches(n Notification) bool {
+	if r.MinSeverity != "" && severityRank(n.Severity) < severityRank(r.MinSeverity) {
+		return false
+	}
+	if len(r.Types) == 0 {
+		return true
+	}
+	for _, t := range r.Types {
+		if t == n.Target.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// SinkResult is the outcome of sending to the Sink of the named Route.
+type SinkResult struct {
+	Name   string
+	Result Result
+	Err    error
+}
+
+// RouterError is returned by Router.Send when some Sinks failed. It holds the
+// SinkResults of those that did.
+type RouterError []SinkResult
+
+func (e RouterError) Error() string {
+	return fmt.Sprintf("minitel: %d sinks failed, first error: %s: %s", len(e), e[0].Name, e[0].Err)
+}
+
+// Router fans Notifications out to the Sinks of every Route matching them.
+type Router struct {
+	routes []Route
+}
+
+// NewRouter returns a Router over routes.
+func NewRouter(routes ...Route) *Router {
+	return &Router{routes: routes}
+}
+
+// Send n to every matching Sink at once. It is validated first, so Sinks that
+// don't validate receive the same Notifications Telex would accept. The
+// SinkResults are in Route order. If any Sink failed the error is a
+// RouterError.
+//
+// If a Route's Sink is a Client with an Environment, the other Sinks are sent
+// n as restricted by the first such Environment, and nothing if it would be
+// dropped, in which case their Result is Suppressed.
+func (r *Router) Send(n Notification) ([]SinkResult, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+
+	var matched []Route
+	var env *Environment
+	for _, route := range r.routes {
+		if route.matches(n) {
+			matched = append(matched, route)
+		}
+		if env == nil {
+			env = sinkEnvironment(route.Sink)
+		}
+	}
+	restricted, suppressed := n, false
+	if env != nil {
+		var err error
+		restricted, err = env.restrict(n, false)
+		suppressed = err != nil
+	}
+
+	results := make([]SinkResult, len(matched))
+	var wg sync.WaitGroup
+	wg.Add(len(matched))
+	for i, route := range matched {
+		go func(i int, route Route) {
+			defer wg.Done()
+			n := n
+			if _, ok := route.Sink.(*Client); !ok && env != nil {
+				if suppressed {
+					results[i] = SinkResult{Name: route.Name, Result: Result{Suppressed: true}}
+					return
+				}
+				n = restricted
+			}
+			res, err := route.Sink.Notify(n)
+			results[i] = SinkResult{Name: route.Name, Result: res, Err: err}
+		}(i, route)
+	}
+	wg.Wait()
+
+	var failed RouterError
+	for _, sr := range results {
+		if sr.Err != nil {
+			failed = append(failed, sr)
+		}
+	}
+	if failed != nil {
+		return results, failed
+	}
+	return results, nil
+}
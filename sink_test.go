@@ -0,0 +1,149 @@
+package minitel
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var got Notification
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "hook-id"}`))
+	}))
+	defer hook.Close()
+
+	n := testNotification()
+	res, err := NewWebhookSink(hook.URL, nil).Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "hook-id" || got.Title != n.Title {
+		t.Errorf("unexpected result %+v or notification %+v", res, got)
+	}
+
+	hook.Config.Handler = http.NotFoundHandler()
+	_, err = NewWebhookSink(hook.URL, nil).Notify(n)
+	if se, ok := err.(*StatusError); !ok || se.Got != http.StatusNotFound {
+		t.Errorf("expected a StatusError for a 404, got %v", err)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minitel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "notifications.jsonl")
+
+	for _, n := range testNotifications(2) {
+		s, err := NewFileSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.Notify(n); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines int
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		var n Notification
+		if err := json.Unmarshal(scanner.Bytes(), &n); err != nil {
+			t.Fatal(err)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("expected both notifications appended, got %d lines", lines)
+	}
+}
+
+func TestRouter(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.ExpectNotify(miniteltest.GenerateHTTPResponse(t, "telex-id", http.StatusCreated))
+
+	c, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var audit, critical bytes.Buffer
+	failing := &fakeNotifier{err: errNoID}
+	r := NewRouter(
+		Route{Name: "telex", Sink: c, Types: []Type{App}},
+		Route{Name: "audit", Sink: NewWriterSink(&audit)},
+		Route{Name: "pager", Sink: NewWriterSink(&critical), MinSeverity: Critical},
+		Route{Name: "users", Sink: failing, Types: []Type{User}},
+	)
+
+	n := testNotification()
+	n.Severity = Warning
+	results, err := r.Send(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "telex" || results[0].Result.ID != "telex-id" || results[1].Name != "audit" {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if audit.Len() == 0 || critical.Len() != 0 {
+		t.Errorf("expected only the audit sink to be written, got %q and %q", audit.String(), critical.String())
+	}
+
+	n.Target.Type = User
+	n.Severity = Critical
+	results, err = r.Send(n)
+	rerr, ok := err.(RouterError)
+	if !ok || len(rerr) != 1 || rerr[0].Name != "users" {
+		t.Fatalf("expected a RouterError for the users sink, got %v", err)
+	}
+	if len(results) != 3 || critical.Len() == 0 {
+		t.Errorf("expected audit, pager and users sinks, got %+v", results)
+	}
+
+	if _, err := r.Send(Notification{}); err != errNoID {
+		t.Errorf("expected invalid notification to be rejected, got %v", err)
+	}
+}
+
+func TestRouterEnvironment(t *testing.T) {
+	c, err := New("http://localhost", WithDryRun(func(DryRunRequest) {}), WithEnvironment(Environment{
+		Name:         "staging",
+		OnSuppressed: func(SuppressedSend) {},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copies := &fakeNotifier{}
+	r := NewRouter(Route{Name: "copies", Sink: copies}, Route{Name: "telex", Sink: c})
+
+	results, err := r.Send(testNotification())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copies.sent) != 0 || !results[0].Result.Suppressed || !results[1].Result.Suppressed {
+		t.Errorf("expected the customer's notification to be dropped by every sink, got %+v and %+v", results, copies.sent)
+	}
+}
@@ -0,0 +1,261 @@
+package minitel
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	errNoPEMCertificates = errors.New("minitel: No PEM certificates found in CA bundle")
+	errNoPeerCertificate = errors.New("minitel: Server presented no certificate")
+	errPinMismatch       = errors.New("minitel: Server certificate does not match any pinned key")
+)
+
+// WithCAFile trusts the PEM encoded CA certificates in the file at path instead
+// of the system roots. The file is reloaded when it changes.
+func WithCAFile(path string) Option {
+	return func(c *Client) error {
+		s := c.tlsSettings()
+		s.caFiles = append(s.caFiles, path)
+		return nil
+	}
+}
+
+// WithCAPEM trusts the PEM encoded CA certificates in pem instead of the system
+// roots.
+func WithCAPEM(pem []byte) Option {
+	return func(c *Client) error {
+		s := c.tlsSettings()
+		s.caPEMs = append(s.caPEMs, pem)
+		return nil
+	}
+}
+
+// WithClientCertFiles presents the PEM encoded certificate and key in the files
+// at certFile and keyFile to Telex. The files are reloaded when they change.
+func WithClientCertFiles(certFile, keyFile string) Option {
+	return func(c *Client) error {
+		s := c.tlsSettings()
+		s.certFile, s.keyFile = certFile, keyFile
+		return nil
+	}
+}
+
+// WithClientCertPEM presents the PEM encoded certificate and key to Telex.
+func WithClientCertPEM(certPEM, keyPEM []byte) Option {
+	return func(c *Client) error {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("minitel: invalid client certificate: %s", err)
+		}
+		c.tlsSettings().cert = &cert
+		return nil
+	}
+}
+
+// WithPinnedKeys only accepts server certificates whose public key matches one
+// of pins, as returned by PublicKeyPin. The certificate must also be trusted.
+func WithPinnedKeys(pins ...string) Option {
+	return func(c *Client) error {
+		s := c.tlsSettings()
+		s.pins = append(s.pins, pins...)
+		return nil
+	}
+}
+
+// PublicKeyPin returns the pin for cert's public key: the base64 encoded
+// SHA-256 hash of its SubjectPublicKeyInfo.
+func PublicKeyPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func (c *Client) tlsSettings() *tlsSettings {
+	if c.tls == nil {
+		c.tls = &tlsSettings{}
+	}
+	return c.tls
+}
+
+// tlsSettings configured by the TLS options and the state needed to reload the
+// files they reference.
+type tlsSettings struct {
+	caFiles           []string
+	caPEMs            [][]byte
+	certFile, keyFile string
+	pins              []string
+
+	mu         sync.Mutex
+	roots      *x509.CertPool
+	rootsMod   time.Time
+	cert       *tls.Certificate
+	certMod    time.Time
+	certLoaded bool
+}
+
+// config returns a copy of base, which may be nil, implementing the settings
+// for connections to serverName. The files they reference are loaded so that
+// problems are reported by New.
+func (s *tlsSettings) config(base *tls.Config, serverName string) (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if base != nil {
+		cfg = base.Clone()
+	}
+
+	if s.certFile != "" || s.cert != nil {
+		if _, err := s.clientCertificate(nil); err != nil {
+			return nil, err
+		}
+		cfg.GetClientCertificate = s.clientCertificate
+	}
+
+	custom := len(s.caFiles) > 0 || len(s.caPEMs) > 0
+	if custom {
+		if _, err := s.rootPool(); err != nil {
+			return nil, err
+		}
+		// Verification is done by VerifyPeerCertificate so that it uses
+		// the current, possibly reloaded, CA bundle.
+		cfg.InsecureSkipVerify = true
+	}
+	if custom || len(s.pins) > 0 {
+		// Resumed sessions skip VerifyPeerCertificate on some Go versions,
+		// which would skip the CA check and pins, so don't resume any.
+		cfg.ClientSessionCache = nil
+		if cfg.ServerName != "" {
+			serverName = cfg.ServerName
+		}
+		verify := cfg.VerifyPeerCertificate
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, chains [][]*x509.Certificate) error {
+			if verify != nil {
+				if err := verify(rawCerts, chains); err != nil {
+					return err
+				}
+			}
+			return s.verifyPeerCertificate(rawCerts, serverName)
+		}
+	}
+	return cfg, nil
+}
+
+func (s *tlsSettings) clientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.certFile == "" {
+		return s.cert, nil
+	}
+	mod, err := latestModTime(s.certFile, s.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if s.certLoaded && !mod.After(s.certMod) {
+		return s.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("minitel: invalid client certificate: %s", err)
+	}
+	s.cert, s.certMod, s.certLoaded = &cert, mod, true
+	return s.cert, nil
+}
+
+func (s *tlsSettings) rootPool() (*x509.CertPool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mod, err := latestModTime(s.caFiles...)
+	if err != nil {
+		return nil, err
+	}
+	if s.roots != nil && !mod.After(s.rootsMod) {
+		return s.roots, nil
+	}
+
+	pool := x509.NewCertPool()
+	pems := append([][]byte(nil), s.caPEMs...)
+	for _, f := range s.caFiles {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		pems = append(pems, b)
+	}
+	for _, pem := range pems {
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errNoPEMCertificates
+		}
+	}
+	s.roots, s.rootsMod = pool, mod
+	return pool, nil
+}
+
+// verifyPeerCertificate checks the certificates presented by serverName
+// against the CA bundle, if one is configured, and the pinned keys.
+func (s *tlsSettings) verifyPeerCertificate(rawCerts [][]byte, serverName string) error {
+	if len(rawCerts) == 0 {
+		return errNoPeerCertificate
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		certs[i] = cert
+	}
+	leaf := certs[0]
+
+	if len(s.caFiles) > 0 || len(s.caPEMs) > 0 {
+		roots, err := s.rootPool()
+		if err != nil {
+			return err
+		}
+		opts := x509.VerifyOptions{
+			DNSName:       serverName,
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range certs[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := leaf.Verify(opts); err != nil {
+			return err
+		}
+	}
+
+	if len(s.pins) == 0 {
+		return nil
+	}
+	pin := PublicKeyPin(leaf)
+	for _, p := range s.pins {
+		if p == pin {
+			return nil
+		}
+	}
+	return errPinMismatch
+}
+
+// latestModTime returns the most recent modification time of the files.
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return latest, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,268 @@
+package minitel
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+// testCA issues certificates for the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "minitel test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{
+		cert: cert,
+		key:  key,
+		pem:  pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// clientCert returns a PEM encoded client certificate and key signed by ca.
+func (ca *testCA) clientCert(t *testing.T) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "minitel test client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kder})
+}
+
+// writeFiles writes each name, contents pair to dir, setting their modification
+// time to mod.
+func writeFiles(t *testing.T, dir string, mod time.Time, files map[string][]byte) {
+	for name, b := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTLSCAPEM(t *testing.T) {
+	ts := miniteltest.NewTLSServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+
+	untrusted, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := untrusted.Notify(testNotification()); err == nil {
+		t.Fatal("expected error using the system roots but was nil")
+	}
+
+	c, err := New(ts.URL, WithCAPEM(ts.CertificatePEM()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Notify(testNotification()); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
+
+func TestTLSCAFileReload(t *testing.T) {
+	ts := miniteltest.NewTLSServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+
+	dir, err := ioutil.TempDir("", "minitel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Now().Add(-time.Hour)
+	writeFiles(t, dir, start, map[string][]byte{"ca.pem": newTestCA(t).pem})
+	c, err := New(ts.URL, WithCAFile(filepath.Join(dir, "ca.pem")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Notify(testNotification()); err == nil {
+		t.Fatal("expected error with the wrong CA but was nil")
+	}
+
+	writeFiles(t, dir, start.Add(time.Minute), map[string][]byte{"ca.pem": ts.CertificatePEM()})
+	if _, err := c.Notify(testNotification()); err != nil {
+		t.Fatal("unexpected error after reloading CA: ", err)
+	}
+}
+
+func TestTLSClientCert(t *testing.T) {
+	ca := newTestCA(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	ts := miniteltest.NewTLSServerWithClientCAs(pool)
+	defer ts.Close()
+	ts.ExpectNotify(nil, nil)
+
+	anonymous, err := New(ts.URL, WithCAPEM(ts.CertificatePEM()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := anonymous.Notify(testNotification()); err == nil {
+		t.Fatal("expected error without a client certificate but was nil")
+	}
+
+	certPEM, keyPEM := ca.clientCert(t)
+	c, err := New(ts.URL, WithCAPEM(ts.CertificatePEM()), WithClientCertPEM(certPEM, keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Notify(testNotification()); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	dir, err := ioutil.TempDir("", "minitel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	start := time.Now().Add(-time.Hour)
+	wrongCert, wrongKey := newTestCA(t).clientCert(t)
+	writeFiles(t, dir, start, map[string][]byte{"cert.pem": wrongCert, "key.pem": wrongKey})
+	c, err = New(ts.URL,
+		WithCAPEM(ts.CertificatePEM()),
+		WithClientCertFiles(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Notify(testNotification()); err == nil {
+		t.Fatal("expected error with an untrusted client certificate but was nil")
+	}
+
+	writeFiles(t, dir, start.Add(time.Minute), map[string][]byte{"cert.pem": certPEM, "key.pem": keyPEM})
+	if _, err := c.Notify(testNotification()); err != nil {
+		t.Fatal("unexpected error after reloading client certificate: ", err)
+	}
+}
+
+func TestTLSPinnedKeys(t *testing.T) {
+	ts := miniteltest.NewTLSServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+
+	c, err := New(ts.URL, WithCAPEM(ts.CertificatePEM()), WithPinnedKeys(PublicKeyPin(ts.Certificate())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Notify(testNotification()); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	c, err = New(ts.URL, WithCAPEM(ts.CertificatePEM()), WithPinnedKeys(PublicKeyPin(newTestCA(t).cert)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Notify(testNotification()); !errors.Is(err, errPinMismatch) {
+		t.Fatalf("expected errPinMismatch, got %v", err)
+	}
+}
+
+func TestTLSMergesTransportConfig(t *testing.T) {
+	ts := miniteltest.NewTLSServer()
+	defer ts.Close()
+	ts.ExpectNotify(nil)
+
+	for _, name := range []string{"example.com", "telex.invalid"} {
+		hc := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				ServerName:         name,
+				MinVersion:         tls.VersionTLS12,
+				ClientSessionCache: tls.NewLRUClientSessionCache(0),
+			},
+		}}
+		c, err := New(ts.URL, WithHTTPClient(hc), WithCAPEM(ts.CertificatePEM()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := c.Client.Transport.(*http.Transport).TLSClientConfig
+		if cfg.MinVersion != tls.VersionTLS12 || cfg.ServerName != name {
+			t.Errorf("expected the transport's TLS config to be kept, got %+v", cfg)
+		}
+		if cfg.ClientSessionCache != nil {
+			t.Error("expected session resumption to be disabled, as it would skip verification")
+		}
+
+		// The test certificate is valid for example.com.
+		_, err = c.Notify(testNotification())
+		if name == "example.com" && err != nil {
+			t.Error("unexpected error: ", err)
+		}
+		if name == "telex.invalid" && err == nil {
+			t.Error("expected error verifying against the configured ServerName but was nil")
+		}
+	}
+}
+
+func TestTLSInvalidOptions(t *testing.T) {
+	if _, err := New("https://localhost", WithCAPEM([]byte("not a certificate"))); err != errNoPEMCertificates {
+		t.Errorf("expected errNoPEMCertificates, got %v", err)
+	}
+	if _, err := New("https://localhost", WithClientCertPEM([]byte("nope"), []byte("nope"))); err == nil {
+		t.Error("expected error for invalid client certificate but was nil")
+	}
+	if _, err := New("https://localhost", WithCAFile("/does/not/exist.pem")); err == nil {
+		t.Error("expected error for missing CA file but was nil")
+	}
+}
@@ -0,0 +1,148 @@
+package minitel
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+var errTLSTransport = errors.New("minitel: TLS options require the http.Client to use a *http.Transport")
+
+// WithHTTPClient sets the http.Client used to make requests, in place of
+// http.DefaultClient. Other options that need a transport build on the one hc
+// uses.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) error {
+		c.Client = hc
+		return nil
+	}
+}
+
+// WithGzip compresses request bodies of at least threshold bytes and asks Telex
+// for compressed responses.
+func WithGzip(threshold int) Option {
+	return func(c *Client) error {
+		if threshold < 0 {
+			return fmt.Errorf("minitel: gzip threshold must not be negative: %d", threshold)
+		}
+		c.gzip = true
+		c.gzipThreshold = threshold
+		return nil
+	}
+}
+
+// configureTransport replaces the http.Client with a copy whose transport
+// implements the options that need one, merging TLS options into any
+// TLSClientConfig it already has. It is called by New once all options have
+// been applied, so replacing Client.Client afterwards drops them.
+func (c *Client) configureTransport() error {
+	if !c.gzip && c.tls == nil {
+		return nil
+	}
+
+	hc := *c.Client
+	base := hc.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	if c.tls != nil {
+		t, ok := base.(*http.Transport)
+		if !ok {
+			return errTLSTransport
+		}
+		u, err := url.Parse(c.url)
+		if err != nil {
+			return err
+		}
+		cfg, err := c.tls.config(t.TLSClientConfig, u.Hostname())
+		if err != nil {
+			return err
+		}
+		t = t.Clone()
+		t.TLSClientConfig = cfg
+		base = t
+	}
+
+	if c.gzip {
+		base = &gzipTransport{base: base, threshold: c.gzipThreshold}
+	}
+	hc.Transport = base
+	c.Client = &hc
+	return nil
+}
+
+// gzipTransport compresses request bodies of at least threshold bytes and
+// decompresses gzip encoded responses.
+type gzipTransport struct {
+	base      http.RoundTripper
+	threshold int
+}
+
+func (t *gzipTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if req.Body != nil && req.Header.Get("Content-Encoding") == "" {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(b) >= t.threshold {
+			if b, err = gzipBytes(b); err != nil {
+				return nil, err
+			}
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		req.ContentLength = int64(len(b))
+		req.Body = ioutil.NopCloser(bytes.NewReader(b))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		}
+	}
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp, err
+	}
+
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = &gzipReadCloser{Reader: zr, body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gzipReadCloser reads the decompressed response and closes the original body.
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
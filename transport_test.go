@@ -0,0 +1,62 @@
+package minitel
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/heroku/minitel-go/miniteltest"
+)
+
+func TestGzip(t *testing.T) {
+	ts := miniteltest.NewServer()
+	defer ts.Close()
+	ts.CompressResponses()
+	ts.ExpectNotify(
+		miniteltest.GenerateHTTPResponse(t, "small", http.StatusCreated),
+		miniteltest.GenerateHTTPResponse(t, "large", http.StatusCreated),
+	)
+
+	c, err := New(ts.URL, WithGzip(1024))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Client == http.DefaultClient {
+		t.Fatal("expected http.DefaultClient not to be modified")
+	}
+
+	n := testNotification()
+	res, err := c.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "small" {
+		t.Errorf("expected compressed response to be decoded, got %+v", res)
+	}
+	if got := ts.CompressedRequests(); got != 0 {
+		t.Errorf("expected small body not to be compressed, got %d compressed requests", got)
+	}
+
+	n.Body = strings.Repeat("release log line\n", 100)
+	res, err = c.Notify(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != "large" {
+		t.Errorf("expected compressed response to be decoded, got %+v", res)
+	}
+	if got := ts.CompressedRequests(); got != 1 {
+		t.Errorf("expected large body to be compressed, got %d compressed requests", got)
+	}
+
+	got := ts.Notifications()
+	if len(got) != 2 || got[1].Body != n.Body {
+		t.Errorf("expected TestServer to decode the compressed body")
+	}
+}
+
+func TestWithGzip(t *testing.T) {
+	if _, err := New("http://localhost", WithGzip(-1)); err == nil {
+		t.Error("expected error for negative threshold but was nil")
+	}
+}